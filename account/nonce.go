@@ -0,0 +1,95 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blocklords/gosds/message"
+)
+
+// The default acceptance window of the nonce timestamp around the server time.
+const NONCE_WINDOW = 30 * time.Second
+
+// The errors returned by NonceVerifier.Verify()
+var (
+	ErrNonceOutOfWindow = errors.New("the nonce timestamp is out of the acceptance window")
+	ErrNonceReused      = errors.New("the nonce was already used")
+)
+
+// Keeps the last accepted nonce of every address.
+//
+// Accept() should atomically compare and store the nonce, so that the
+// same nonce is never accepted twice, even by the parallel requests.
+type NonceStore interface {
+	// Returns true and stores the nonce, if it's greater than the last accepted nonce of the address.
+	// Otherwise returns false.
+	Accept(address string, nonce uint64) (bool, error)
+}
+
+// Protects against the replay of the signed SmartcontractDeveloperRequest.
+//
+// The nonce timestamp of the request should be within the window around the server time,
+// and greater than the last accepted nonce of the address.
+// Therefore an address can send one request per second at most.
+type NonceVerifier struct {
+	window time.Duration
+	store  NonceStore
+}
+
+// Creates a new verifier. If the window is 0, then NONCE_WINDOW is used.
+func NewNonceVerifier(store NonceStore, window time.Duration) *NonceVerifier {
+	if window <= 0 {
+		window = NONCE_WINDOW
+	}
+	return &NonceVerifier{window: window, store: store}
+}
+
+// Checks the nonce of the request. The request's signature should be verified before,
+// otherwise anyone could use the nonce of the address.
+//
+// The returned errors could be checked against ErrNonceOutOfWindow and ErrNonceReused by errors.Is().
+func (verifier *NonceVerifier) Verify(request *message.SmartcontractDeveloperRequest) error {
+	now := time.Now()
+	nonce := time.Unix(int64(request.NonceTimestamp), 0)
+	if nonce.Before(now.Add(-verifier.window)) || nonce.After(now.Add(verifier.window)) {
+		return fmt.Errorf("%w: the nonce %d, the server time %d, the window %s", ErrNonceOutOfWindow, request.NonceTimestamp, now.Unix(), verifier.window)
+	}
+
+	accepted, err := verifier.store.Accept(strings.ToLower(request.Address), request.NonceTimestamp)
+	if err != nil {
+		return errors.New("failed to check the nonce: " + err.Error())
+	}
+	if !accepted {
+		return ErrNonceReused
+	}
+
+	return nil
+}
+
+// Keeps the nonces in the memory.
+// The nonces are lost when the service restarts, but
+// the old requests are rejected by the acceptance window anyway.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]uint64
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]uint64{}}
+}
+
+func (store *MemoryNonceStore) Accept(address string, nonce uint64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	last, exists := store.nonces[address]
+	if exists && nonce <= last {
+		return false, nil
+	}
+	store.nonces[address] = nonce
+
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blocklords/gosds/message"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Verifies the signature of the SmartcontractDeveloperRequest,
+// and returns the account that signed the request.
+type SignatureVerifier interface {
+	Verify(request *message.SmartcontractDeveloperRequest) (*SmartcontractDeveloper, error)
+}
+
+var (
+	verifiers_mu sync.RWMutex
+	verifiers    = map[string]SignatureVerifier{
+		message.PERSONAL_SIGN_SCHEME: EcdsaVerifier{},
+		message.EIP712_SCHEME:        EcdsaVerifier{},
+		message.ED25519_SCHEME:       Ed25519Verifier{},
+	}
+)
+
+// Sets the verifier of the signature scheme.
+// It's used by NewSmartcontractDeveloper() for the requests with this scheme.
+func RegisterSignatureVerifier(scheme string, verifier SignatureVerifier) {
+	verifiers_mu.Lock()
+	verifiers[scheme] = verifier
+	verifiers_mu.Unlock()
+}
+
+// Returns the verifier of the signature scheme.
+// The empty scheme is message.PERSONAL_SIGN_SCHEME.
+func GetSignatureVerifier(scheme string) (SignatureVerifier, error) {
+	if scheme == "" {
+		scheme = message.PERSONAL_SIGN_SCHEME
+	}
+
+	verifiers_mu.RLock()
+	verifier, ok := verifiers[scheme]
+	verifiers_mu.RUnlock()
+	if !ok {
+		return nil, errors.New("unsupported signature scheme '" + scheme + "'")
+	}
+
+	return verifier, nil
+}
+
+// Verifies the Ethereum signature of the message.PERSONAL_SIGN_SCHEME and message.EIP712_SCHEME.
+// The address of the request is the address of the public key derived from the signature.
+type EcdsaVerifier struct{}
+
+func (EcdsaVerifier) Verify(request *message.SmartcontractDeveloperRequest) (*SmartcontractDeveloper, error) {
+	// without 0x prefix
+	signature, err := hexutil.Decode(request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	digested_hash := request.DigestedMessage()
+	if len(digested_hash) == 0 {
+		return nil, errors.New("failed to digest the request")
+	}
+
+	if len(signature) != 65 {
+		return nil, errors.New("the ECDSA signature length is invalid. It should be 64 bytes long. Signature length: ")
+	}
+	if signature[64] != 27 && signature[64] != 28 {
+		return nil, errors.New("invalid Ethereum signature (V is not 27 or 28)")
+	}
+	signature[64] -= 27 // Transform yellow paper V from 27/28 to 0/1
+
+	ecdsa_public_key, err := crypto.SigToPub(digested_hash, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	address := crypto.PubkeyToAddress(*ecdsa_public_key).Hex()
+	if !strings.EqualFold(address, request.Address) {
+		return nil, errors.New("the request 'address' parameter mismatches to the account derived from signature. Account derived from the signature: " + address + "...")
+	}
+
+	return NewEcdsaPublicKey(ecdsa_public_key), nil
+}
+
+// Verifies the signature of the message.ED25519_SCHEME.
+// The address of the request is the hex of the public key.
+type Ed25519Verifier struct{}
+
+func (Ed25519Verifier) Verify(request *message.SmartcontractDeveloperRequest) (*SmartcontractDeveloper, error) {
+	pub_key, err := hexutil.Decode(request.Address)
+	if err != nil {
+		return nil, errors.New("the request 'address' is not the hex of ed25519 public key: " + err.Error())
+	}
+	if len(pub_key) != ed25519.PublicKeySize {
+		return nil, errors.New("the request 'address' is not the ed25519 public key, it should be 32 bytes long")
+	}
+	signature, err := hexutil.Decode(request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return nil, errors.New("the ed25519 signature length is invalid. It should be 64 bytes long")
+	}
+	digested_hash := request.DigestedMessage()
+	if len(digested_hash) == 0 {
+		return nil, errors.New("failed to digest the request")
+	}
+
+	if !ed25519.Verify(pub_key, digested_hash, signature) {
+		return nil, errors.New("invalid ed25519 signature")
+	}
+
+	return NewEd25519PublicKey(pub_key), nil
+}
+
+// The default timeout of the isValidSignature() call
+const EIP1271_TIMEOUT = 10 * time.Second
+
+// The value returned by isValidSignature(bytes32,bytes) if the signature is valid.
+// It's the method selector.
+var eip1271_magic_value = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// Verifies the signature of the message.EIP1271_SCHEME.
+// The address of the request is the contract wallet,
+// and the signature is checked by the contract's isValidSignature() method over the EIP-712 digest.
+//
+// Register it for the SDS Service:
+//
+//	client, _ := ethclient.Dial(url)
+//	account.RegisterSignatureVerifier(message.EIP1271_SCHEME, account.NewEip1271Verifier(client, 0))
+type Eip1271Verifier struct {
+	caller  ethereum.ContractCaller
+	timeout time.Duration
+}
+
+// Creates a new verifier that calls the contracts by the caller, for example *ethclient.Client.
+// If the timeout is 0, then EIP1271_TIMEOUT is used.
+func NewEip1271Verifier(caller ethereum.ContractCaller, timeout time.Duration) *Eip1271Verifier {
+	if timeout <= 0 {
+		timeout = EIP1271_TIMEOUT
+	}
+	return &Eip1271Verifier{caller: caller, timeout: timeout}
+}
+
+func (verifier *Eip1271Verifier) Verify(request *message.SmartcontractDeveloperRequest) (*SmartcontractDeveloper, error) {
+	if !common.IsHexAddress(request.Address) {
+		return nil, errors.New("the request 'address' is not a valid contract address")
+	}
+	signature, err := hexutil.Decode(request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	digested_hash := request.DigestedMessage()
+	if len(digested_hash) == 0 {
+		return nil, errors.New("failed to digest the request")
+	}
+
+	contract := common.HexToAddress(request.Address)
+	ctx, cancel := context.WithTimeout(context.Background(), verifier.timeout)
+	defer cancel()
+
+	result, err := verifier.caller.CallContract(ctx, ethereum.CallMsg{
+		To:   &contract,
+		Data: eip1271_call_data(digested_hash, signature),
+	}, nil)
+	if err != nil {
+		return nil, errors.New("failed to call isValidSignature of " + contract.Hex() + ": " + err.Error())
+	}
+	if len(result) < 4 || !bytes.Equal(result[:4], eip1271_magic_value) {
+		return nil, errors.New("the contract " + contract.Hex() + " rejected the signature")
+	}
+
+	return NewContractAccount(contract.Hex()), nil
+}
+
+// The ABI encoded call of isValidSignature(bytes32 hash, bytes signature)
+func eip1271_call_data(hash []byte, signature []byte) []byte {
+	data := make([]byte, 0, 4+32*4+len(signature))
+	data = append(data, eip1271_magic_value...)
+	data = append(data, common.LeftPadBytes(hash, 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(64).Bytes(), 32)...) // the offset of the signature
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(signature))).Bytes(), 32)...)
+	data = append(data, common.RightPadBytes(signature, (len(signature)+31)/32*32)...)
+	return data
+}
@@ -3,25 +3,45 @@ package account
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"errors"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/blocklords/gosds/message"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
 )
 
-const ECDSA uint8 = 1
+// The cryptographic algorithms of the account
+const (
+	ECDSA    uint8 = 1
+	ED25519  uint8 = 2
+	CONTRACT uint8 = 3 // EIP-1271 contract wallet, it has no keys
+)
 
 type SmartcontractDeveloper struct {
-	Address         string
-	AccountType     uint8             // The cryptographic algorithm key
-	EcdsaPublicKey  *ecdsa.PublicKey  // If the account type is ECDSA, then this one will keep the pub key
-	EcdsaPrivateKey *ecdsa.PrivateKey //
+	Address           string
+	AccountType       uint8              // The cryptographic algorithm key
+	EcdsaPublicKey    *ecdsa.PublicKey   // If the account type is ECDSA, then this one will keep the pub key
+	EcdsaPrivateKey   *ecdsa.PrivateKey  //
+	Ed25519PublicKey  ed25519.PublicKey  // If the account type is ED25519, then this one will keep the pub key
+	Ed25519PrivateKey ed25519.PrivateKey //
+	SignatureScheme   string             // The scheme used by Sign() for ECDSA, message.PERSONAL_SIGN_SCHEME if empty
+
+	nonce_mu   sync.Mutex
+	last_nonce uint64     // the last nonce timestamp used by Sign()
+	send_mu    sync.Mutex // held by SignAndSend() from the signing till the reply
 }
 
+// How far the nonce timestamp of the account could be ahead of the clock.
+// It's less than NONCE_WINDOW, so the SDS Service accepts the nonce.
+// Sign() waits if the account sent more requests than one per second.
+const MAX_NONCE_LEAD = 10 * time.Second
+
 // Creates a new SmartcontractDeveloper with a public key but without private key
 func NewEcdsaPublicKey(pub_key *ecdsa.PublicKey) *SmartcontractDeveloper {
 	return &SmartcontractDeveloper{
@@ -42,39 +62,113 @@ func NewEcdsaPrivateKey(private_key *ecdsa.PrivateKey) *SmartcontractDeveloper {
 	}
 }
 
+// Creates a new SmartcontractDeveloper with an ed25519 public key.
+// The address is the hex of the public key.
+func NewEd25519PublicKey(pub_key ed25519.PublicKey) *SmartcontractDeveloper {
+	return &SmartcontractDeveloper{
+		Address:          hexutil.Encode(pub_key),
+		AccountType:      ED25519,
+		Ed25519PublicKey: pub_key,
+	}
+}
+
+// Creates a new SmartcontractDeveloper with an ed25519 private key
+func NewEd25519PrivateKey(private_key ed25519.PrivateKey) *SmartcontractDeveloper {
+	pub_key := private_key.Public().(ed25519.PublicKey)
+	return &SmartcontractDeveloper{
+		Address:           hexutil.Encode(pub_key),
+		AccountType:       ED25519,
+		Ed25519PublicKey:  pub_key,
+		Ed25519PrivateKey: private_key,
+	}
+}
+
+// Creates a new SmartcontractDeveloper of the contract wallet.
+// It can't sign or decrypt, the owners of the contract sign the requests.
+func NewContractAccount(address string) *SmartcontractDeveloper {
+	return &SmartcontractDeveloper{
+		Address:     common.HexToAddress(address).Hex(),
+		AccountType: CONTRACT,
+	}
+}
+
 // Get the account who did the request.
 // Account is verified first using the signature parameter of the request.
 // If the signature is not a valid, then returns an error.
 //
-// For now it supports ECDSA addresses only. Therefore verification automatically assumes that address
-// is for the ethereum network.
+// The signature is verified by the SignatureVerifier of the request.SignatureScheme.
+// ECDSA (personal_sign and EIP-712) and ed25519 are supported by default.
+// The EIP-1271 contract wallets should be enabled by RegisterSignatureVerifier(), see NewEip1271Verifier().
 func NewSmartcontractDeveloper(request *message.SmartcontractDeveloperRequest) (*SmartcontractDeveloper, error) {
-	// without 0x prefix
-	signature, err := hexutil.Decode(request.Signature)
+	verifier, err := GetSignatureVerifier(request.SignatureScheme)
 	if err != nil {
 		return nil, err
 	}
-	digested_hash := request.DigestedMessage()
 
-	if len(signature) != 65 {
-		return nil, errors.New("the ECDSA signature length is invalid. It should be 64 bytes long. Signature length: ")
+	return verifier.Verify(request)
+}
+
+// Signs the request by the private key of the account.
+// It sets the address, the nonce timestamp and the signature of the request.
+// If the request has no signature scheme, then the account's SignatureScheme is used.
+//
+// The nonce timestamp is the current time in seconds, but always greater than the previous nonce
+// of the account, since the SDS Service with the NonceVerifier rejects the reused nonces.
+// If the nonce would be ahead of the clock by more than MAX_NONCE_LEAD, then Sign() waits.
+//
+// Such SDS Service also rejects the nonce that is lower than the last accepted one.
+// Use SignAndSend() to deliver the requests signed in parallel in the order of their nonces.
+func (account *SmartcontractDeveloper) Sign(request *message.SmartcontractDeveloperRequest) error {
+	switch account.AccountType {
+	case ECDSA:
+		if account.EcdsaPrivateKey == nil {
+			return errors.New("the account has no private key")
+		}
+	case ED25519:
+		if account.Ed25519PrivateKey == nil {
+			return errors.New("the account has no private key")
+		}
+	default:
+		return errors.New("only ECDSA and ed25519 accounts could sign")
+	}
+
+	account.nonce_mu.Lock()
+	now := uint64(time.Now().Unix())
+	nonce := now
+	if nonce <= account.last_nonce {
+		nonce = account.last_nonce + 1
 	}
-	if signature[64] != 27 && signature[64] != 28 {
-		return nil, errors.New("invalid Ethereum signature (V is not 27 or 28)")
+	max_lead := uint64(MAX_NONCE_LEAD / time.Second)
+	if nonce-now > max_lead {
+		// the other requests of the account wait too, they would need the later nonces anyway
+		time.Sleep(time.Duration(nonce-now-max_lead) * time.Second)
 	}
-	signature[64] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	account.last_nonce = nonce
+	account.nonce_mu.Unlock()
 
-	ecdsa_public_key, err := crypto.SigToPub(digested_hash, signature)
-	if err != nil {
-		return nil, err
+	request.NonceTimestamp = nonce
+	if account.AccountType == ED25519 {
+		return request.SignEd25519(account.Ed25519PrivateKey)
 	}
+	if request.SignatureScheme == "" {
+		request.SignatureScheme = account.SignatureScheme
+	}
+	return request.Sign(account.EcdsaPrivateKey)
+}
+
+// Signs the request, then sends it by the send function.
+// The other requests of the account are neither signed nor sent until the send function returns.
+// Therefore the SDS Service receives the nonces of the account in order, even if
+// the requests are sent in parallel over the socket pool.
+func (account *SmartcontractDeveloper) SignAndSend(request *message.SmartcontractDeveloperRequest, send func(*message.SmartcontractDeveloperRequest) error) error {
+	account.send_mu.Lock()
+	defer account.send_mu.Unlock()
 
-	address := crypto.PubkeyToAddress(*ecdsa_public_key).Hex()
-	if !strings.EqualFold(address, request.Address) {
-		return nil, errors.New("the request 'address' parameter mismatches to the account derived from signature. Account derived from the signature: " + address + "...")
+	if err := account.Sign(request); err != nil {
+		return err
 	}
 
-	return NewEcdsaPublicKey(ecdsa_public_key), nil
+	return send(request)
 }
 
 // Encrypts the given data with a public key
@@ -83,7 +177,7 @@ func NewSmartcontractDeveloper(request *message.SmartcontractDeveloperRequest) (
 // If the account has a private key, then the public key derived from it would be used
 func (account *SmartcontractDeveloper) Encrypt(plain_text []byte) ([]byte, error) {
 	if account.AccountType != ECDSA {
-		return []byte{}, errors.New("only ECDSA protocol supported")
+		return []byte{}, errors.New("only ECDSA protocol supports the encryption")
 	}
 	if account.EcdsaPrivateKey != nil {
 		account.EcdsaPublicKey = &account.EcdsaPrivateKey.PublicKey
@@ -104,7 +198,7 @@ func (account *SmartcontractDeveloper) Encrypt(plain_text []byte) ([]byte, error
 
 func (account *SmartcontractDeveloper) Decrypt(cipher_text []byte) ([]byte, error) {
 	if account.AccountType != ECDSA {
-		return []byte{}, errors.New("only ECDSA is supported")
+		return []byte{}, errors.New("only ECDSA protocol supports the decryption")
 	}
 
 	if account.EcdsaPrivateKey == nil {
@@ -18,6 +18,8 @@ const (
 	//    support only network id 5
 	NETWORK_ID = "network-id"
 	NO_EVENT   = "no-event" // smartcontract events are not supported by the SDS Service
+
+	LEGACY_BROADCAST = "legacy-broadcast" // accept the broadcasts in the format before the multi-frame envelope
 )
 
 // any command line data that comes after the files are .env file paths
@@ -2,7 +2,10 @@
 package broadcast
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/blocklords/gosds/argument"
 	"github.com/blocklords/gosds/env"
@@ -12,6 +15,9 @@ import (
 	zmq "github.com/pebbe/zmq4"
 )
 
+// On stop, how long the publisher waits for the queued messages to be sent to subscribers.
+const flush_timeout = time.Second
+
 // Run a new broadcaster
 //
 // It assumes that the another package is starting an authentication layer of zmq:
@@ -19,6 +25,39 @@ import (
 //
 // If some error is encountered, then this package panics
 func Run(channel chan message.Broadcast, broadcast_env *env.Env, whitelisted_users []*env.Env) {
+	if err := RunContext(context.Background(), channel, broadcast_env, whitelisted_users); err != nil {
+		panic(err)
+	}
+}
+
+// Run the broadcaster in the background.
+//
+// The returned channel receives the error if the broadcaster stopped because of it.
+// The channel is closed once the broadcaster stops.
+func Start(ctx context.Context, channel chan message.Broadcast, broadcast_env *env.Env, whitelisted_users []*env.Env) <-chan error {
+	error_channel := make(chan error, 1)
+
+	go func() {
+		if err := RunContext(ctx, channel, broadcast_env, whitelisted_users); err != nil {
+			error_channel <- err
+		}
+		close(error_channel)
+	}()
+
+	return error_channel
+}
+
+// Run a new broadcaster until the context is done.
+//
+// Every broadcast is numbered with the sequence of its topic, starting from 1.
+// Even if there is no data, the heartbeat is sent every message.HEARTBEAT_INTERVAL
+// on message.HEARTBEAT_TOPIC.
+//
+// When the context is done, the broadcasts that are already in the channel are sent,
+// then the socket is closed and nil is returned.
+//
+// Unlike Run() it returns the error instead of panicking.
+func RunContext(ctx context.Context, channel chan message.Broadcast, broadcast_env *env.Env, whitelisted_users []*env.Env) error {
 	public_keys := make([]string, len(whitelisted_users))
 	for k, v := range whitelisted_users {
 		public_keys[k] = v.BroadcastPublicKey()
@@ -26,7 +65,7 @@ func Run(channel chan message.Broadcast, broadcast_env *env.Env, whitelisted_use
 
 	plain, err := argument.Exist(argument.PLAIN)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	domain_name := ""
@@ -39,24 +78,73 @@ func Run(channel chan message.Broadcast, broadcast_env *env.Env, whitelisted_use
 	// prepare the publisher
 	pub, err := zmq.NewSocket(zmq.PUB)
 	if err != nil {
-		panic("error while trying to create a new socket " + err.Error())
+		return errors.New("error while trying to create a new socket " + err.Error())
 	}
 	defer pub.Close()
 	if !plain {
-		pub.ServerAuthCurve(domain_name, broadcast_env.BroadcastSecretKey())
+		if err := pub.ServerAuthCurve(domain_name, broadcast_env.BroadcastSecretKey()); err != nil {
+			return fmt.Errorf("could not set the publisher authentication: %w", err)
+		}
 	}
 
 	err = pub.Bind("tcp://*:" + broadcast_env.BroadcastPort())
 	if err != nil {
-		log.Fatalf("could not listen to publisher: %v", err)
+		return fmt.Errorf("could not listen to publisher: %w", err)
 	}
 
+	sequences := make(sequences)
+
+	heartbeat := time.NewTicker(message.HEARTBEAT_INTERVAL)
+	defer heartbeat.Stop()
+
 	for {
-		broadcast := <-channel
+		select {
+		case <-ctx.Done():
+			return flush(pub, channel, sequences)
+		case <-heartbeat.C:
+			if err := send(pub, message.NewHeartbeat(), sequences); err != nil {
+				return err
+			}
+		case broadcast := <-channel:
+			if err := send(pub, broadcast, sequences); err != nil {
+				return err
+			}
+		}
+	}
+}
 
-		_, err = pub.SendMessage(broadcast.Topic, broadcast.ToBytes())
-		if err != nil {
-			log.Fatal(err)
+// The last sequence number of each topic
+type sequences map[string]uint64
+
+// Number the broadcast, then send it to the subscribers.
+//
+// The broadcast that can't be encoded is skipped without taking the sequence number,
+// so the subscribers don't see the gap.
+func send(pub *zmq.Socket, broadcast message.Broadcast, sequences sequences) error {
+	broadcast.Sequence = sequences[broadcast.Topic] + 1
+
+	frames, err := broadcast.ToFrames()
+	if err != nil {
+		fmt.Println("failed to encode the broadcast of the topic '"+broadcast.Topic+"', skipping it: ", err)
+		return nil
+	}
+	sequences[broadcast.Topic] = broadcast.Sequence
+
+	_, err = pub.SendMessage(frames)
+	return err
+}
+
+// Sends the broadcasts that are waiting in the channel,
+// then gives some time for the socket to deliver them.
+func flush(pub *zmq.Socket, channel chan message.Broadcast, sequences sequences) error {
+	for {
+		select {
+		case broadcast := <-channel:
+			if err := send(pub, broadcast, sequences); err != nil {
+				return err
+			}
+		default:
+			return pub.SetLinger(flush_timeout)
 		}
 	}
 }
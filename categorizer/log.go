@@ -111,28 +111,55 @@ func ParseLog(blob map[string]interface{}) (*Log, error) {
 	return &log, nil
 }
 
+// The parameters of the "log_get_all" command
+type LogsRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// The reply of the "log_get_all" command.
+// Use ParseLog() for each of the logs.
+type LogsReply struct {
+	Logs []map[string]interface{} `json:"logs"`
+}
+
+// The parameters of the "parse" command
+type LogParseRequest struct {
+	NetworkId string   `json:"network_id"`
+	Address   string   `json:"address"`
+	Data      string   `json:"data"`
+	Topics    []string `json:"topics"`
+}
+
+// The reply of the "parse" command
+type LogParseReply struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
 // Return list of logs for the transaction keys from the remote SDS Categorizer.
 // For the transaction keys see
 // github.com/blocklords/gosds/categorizer/transaction.go TransactionKey()
-func RemoteLogs(socket *remote.Socket, keys []string) ([]*Log, error) {
+func RemoteLogs(socket remote.Requester, keys []string) ([]*Log, error) {
+	parameters, err := message.EncodeParams(LogsRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "log_get_all",
-		Parameters: map[string]interface{}{
-			"keys": keys,
-		},
+		Command:    "log_get_all",
+		Parameters: parameters,
 	}
 	params, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	raw_logs, err := message.GetMapList(params, "logs")
+	reply, err := message.DecodeParams[LogsReply](params)
 	if err != nil {
 		return nil, err
 	}
 
-	logs := make([]*Log, len(raw_logs))
-	for i, raw := range raw_logs {
+	logs := make([]*Log, len(reply.Logs))
+	for i, raw := range reply.Logs {
 		log, err := ParseLog(raw)
 		if err != nil {
 			return nil, err
@@ -146,15 +173,19 @@ func RemoteLogs(socket *remote.Socket, keys []string) ([]*Log, error) {
 // Parse the raw event data using SDS Log.
 // parsing events using JSON abi is harder in golang, therefore we use javascript
 // implementation called SDS Log.
-func RemoteLogParse(socket *remote.Socket, network_id string, address string, data string, topics []string) (string, map[string]interface{}, error) {
+func RemoteLogParse(socket remote.Requester, network_id string, address string, data string, topics []string) (string, map[string]interface{}, error) {
+	parameters, err := message.EncodeParams(LogParseRequest{
+		NetworkId: network_id,
+		Address:   address,
+		Data:      data,
+		Topics:    topics,
+	})
+	if err != nil {
+		return "", nil, err
+	}
 	request := message.Request{
-		Command: "parse",
-		Parameters: map[string]interface{}{
-			"network_id": network_id,
-			"address":    address,
-			"data":       data,
-			"topics":     topics,
-		},
+		Command:    "parse",
+		Parameters: parameters,
 	}
 
 	params, err := socket.RequestRemoteService(&request)
@@ -162,14 +193,10 @@ func RemoteLogParse(socket *remote.Socket, network_id string, address string, da
 		return "", nil, err
 	}
 
-	name, err := message.GetString(params, "name")
-	if err != nil {
-		return "", nil, err
-	}
-	args, err := message.GetMap(params, "args")
+	reply, err := message.DecodeParams[LogParseReply](params)
 	if err != nil {
 		return "", nil, err
 	}
 
-	return name, args, nil
+	return reply.Name, reply.Args, nil
 }
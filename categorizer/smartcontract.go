@@ -81,7 +81,7 @@ func (b *Smartcontract) ToString() (string, error) {
 }
 
 // Sends a command to the remote SDS Categorizer about regitration of this smartcontract.
-func (b *Smartcontract) RemoteSet(socket *remote.Socket) error {
+func (b *Smartcontract) RemoteSet(socket remote.Requester) error {
 	// Send hello.
 	request := message.Request{
 		Command:    "smartcontract_set",
@@ -96,32 +96,49 @@ func (b *Smartcontract) RemoteSet(socket *remote.Socket) error {
 	return nil
 }
 
+// The parameters of the "smartcontract_get" command
+type SmartcontractRequest struct {
+	NetworkId string `json:"network_id"`
+	Address   string `json:"address"`
+}
+
+// The reply of the "smartcontract_get" command.
+// Use ParseSmartcontract() for the smartcontract.
+type SmartcontractReply struct {
+	Smartcontract map[string]interface{} `json:"smartcontract"`
+}
+
+// The reply of the "smartcontract_get_all" command.
+// Use ParseSmartcontract() for each of the smartcontracts.
+type SmartcontractsReply struct {
+	Smartcontracts []map[string]interface{} `json:"smartcontracts"`
+}
+
 // Returns a smartcontract information from the remote SDS Categorizer.
-func RemoteSmartcontract(socket *remote.Socket, network_id string, address string) (*Smartcontract, error) {
-	// Send hello.
+func RemoteSmartcontract(socket remote.Requester, network_id string, address string) (*Smartcontract, error) {
+	parameters, err := message.EncodeParams(SmartcontractRequest{NetworkId: network_id, Address: address})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "smartcontract_get",
-		Parameters: map[string]interface{}{
-			"network_id": network_id,
-			"address":    address,
-		},
+		Command:    "smartcontract_get",
+		Parameters: parameters,
 	}
 	params, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	smartcontract, err := message.GetMap(params, "smartcontract")
+	reply, err := message.DecodeParams[SmartcontractReply](params)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseSmartcontract(smartcontract)
+	return ParseSmartcontract(reply.Smartcontract)
 }
 
 // Returns all smartcontracts from SDS Categorizer
-func RemoteSmartcontracts(socket *remote.Socket) ([]*Smartcontract, error) {
-	// Send hello.
+func RemoteSmartcontracts(socket remote.Requester) ([]*Smartcontract, error) {
 	request := message.Request{
 		Command:    "smartcontract_get_all",
 		Parameters: map[string]interface{}{},
@@ -132,13 +149,13 @@ func RemoteSmartcontracts(socket *remote.Socket) ([]*Smartcontract, error) {
 		return nil, err
 	}
 
-	raw_smartcontracts, err := message.GetMapList(params, "smartcontracts")
+	reply, err := message.DecodeParams[SmartcontractsReply](params)
 	if err != nil {
 		return nil, err
 	}
 
-	smartcontracts := make([]*Smartcontract, len(raw_smartcontracts))
-	for i, raw := range raw_smartcontracts {
+	smartcontracts := make([]*Smartcontract, len(reply.Smartcontracts))
+	for i, raw := range reply.Smartcontracts {
 		smartcontract, err := ParseSmartcontract(raw)
 		if err != nil {
 			return nil, err
@@ -1,6 +1,8 @@
 package categorizer
 
 import (
+	"math/big"
+
 	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
 	"github.com/blocklords/gosds/spaghetti"
@@ -17,7 +19,7 @@ type Transaction struct {
 	TxFrom         string
 	Method         string
 	Args           map[string]interface{}
-	Value          float64
+	Value          *big.Int // in wei
 }
 
 func TransactionKey(networkId string, txId string) string {
@@ -35,7 +37,12 @@ func (b *Transaction) ToJSON() map[string]interface{} {
 	i["tx_from"] = b.TxFrom
 	i["method"] = b.Method
 	i["arguments"] = b.Args
-	i["value"] = b.Value
+	// as a string, since the JSON number would lose the precision of wei
+	if b.Value == nil {
+		i["value"] = "0"
+	} else {
+		i["value"] = b.Value.String()
+	}
 	return i
 }
 
@@ -92,7 +99,7 @@ func ParseTransaction(blob map[string]interface{}) (*Transaction, error) {
 		return nil, err
 	}
 
-	value, err := message.GetFloat64(blob, "value")
+	value, err := message.GetBigInt(blob, "value")
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +123,9 @@ func (transaction *Transaction) AddMetadata(spaghetti_transaction *spaghetti.Tra
 	transaction.Txid = spaghetti_transaction.Txid
 	transaction.TxIndex = spaghetti_transaction.TxIndex
 	transaction.TxFrom = spaghetti_transaction.TxFrom
-	transaction.Value = spaghetti_transaction.Value
+	if spaghetti_transaction.Value != nil {
+		transaction.Value = new(big.Int).Set(spaghetti_transaction.Value)
+	}
 
 	return transaction
 }
@@ -128,39 +137,77 @@ func (transaction *Transaction) AddSmartcontractData(smartcontract *Smartcontrac
 	return transaction
 }
 
+// The parameters of the "transaction_amount" command
+type TransactionAmountRequest struct {
+	BlockTimestampFrom int      `json:"block_timestamp_from"`
+	BlockTimestampTo   int      `json:"block_timestamp_to"`
+	SmartcontractKeys  []string `json:"smartcontract_keys"`
+}
+
+// The parameters of the "transaction_get_all" command
+type TransactionsRequest struct {
+	BlockTimestampFrom int      `json:"block_timestamp_from"`
+	BlockTimestampTo   int      `json:"block_timestamp_to"`
+	SmartcontractKeys  []string `json:"smartcontract_keys"`
+	Page               int      `json:"page"`
+	Limit              uint     `json:"limit"`
+}
+
+// The reply of the "transaction_amount" command
+type TransactionAmountReply struct {
+	TransactionAmount int `json:"transaction_amount"`
+}
+
+// The reply of the "transaction_get_all" command.
+// Use ParseTransaction() for each of the transactions.
+type TransactionsReply struct {
+	Transactions []map[string]interface{} `json:"transactions"`
+}
+
 // Returns amount of transactions for the smartcontract keys within a certain block timestamp range.
-func RemoteTransactionAmount(socket *remote.Socket, blockTimestampFrom int, blockTimestampTo int, smartcontractKeys []string) (int, error) {
+func RemoteTransactionAmount(socket remote.Requester, blockTimestampFrom int, blockTimestampTo int, smartcontractKeys []string) (int, error) {
+	parameters, err := message.EncodeParams(TransactionAmountRequest{
+		BlockTimestampFrom: blockTimestampFrom,
+		BlockTimestampTo:   blockTimestampTo,
+		SmartcontractKeys:  smartcontractKeys,
+	})
+	if err != nil {
+		return 0, err
+	}
 	request := message.Request{
-		Command: "transaction_amount",
-		Parameters: map[string]interface{}{
-			"block_timestamp_from": blockTimestampFrom,
-			"block_timestamp_to":   blockTimestampTo,
-			"smartcontract_keys":   smartcontractKeys,
-		},
+		Command:    "transaction_amount",
+		Parameters: parameters,
 	}
 	params, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return 0, err
 	}
 
-	txAmount := int(params["transaction_amount"].(float64))
+	reply, err := message.DecodeParams[TransactionAmountReply](params)
+	if err != nil {
+		return 0, err
+	}
 
-	return txAmount, nil
+	return reply.TransactionAmount, nil
 }
 
 // Return transactions for smartcontract keys within a certain time range.
 //
 // It accepts a page and limit
-func RemoteTransactions(socket *remote.Socket, blockTimestampFrom int, blockTimestampTo int, smartcontractKeys []string, page int, limit uint) ([]*Transaction, error) {
+func RemoteTransactions(socket remote.Requester, blockTimestampFrom int, blockTimestampTo int, smartcontractKeys []string, page int, limit uint) ([]*Transaction, error) {
+	parameters, err := message.EncodeParams(TransactionsRequest{
+		BlockTimestampFrom: blockTimestampFrom,
+		BlockTimestampTo:   blockTimestampTo,
+		SmartcontractKeys:  smartcontractKeys,
+		Page:               page,
+		Limit:              limit,
+	})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "transaction_get_all",
-		Parameters: map[string]interface{}{
-			"block_timestamp_from": blockTimestampFrom,
-			"block_timestamp_to":   blockTimestampTo,
-			"smartcontract_keys":   smartcontractKeys,
-			"page":                 page,
-			"limit":                limit,
-		},
+		Command:    "transaction_get_all",
+		Parameters: parameters,
 	}
 
 	params, err := socket.RequestRemoteService(&request)
@@ -168,10 +215,14 @@ func RemoteTransactions(socket *remote.Socket, blockTimestampFrom int, blockTime
 		return nil, err
 	}
 
-	raws := params["transactions"].([]interface{})
-	transactions := make([]*Transaction, len(raws))
-	for i, raw := range raws {
-		transactions[i], err = ParseTransaction(raw.(map[string]interface{}))
+	reply, err := message.DecodeParams[TransactionsReply](params)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*Transaction, len(reply.Transactions))
+	for i, raw := range reply.Transactions {
+		transactions[i], err = ParseTransaction(raw)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/blocklords/gosds/message"
+)
+
+// Prints the command and the status of the reply.
+// If the request has the header, then the request id and the traceparent are printed too.
+func Logging() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *HandlerContext) message.Reply {
+			reply := next(c)
+			label := "command '" + c.Request.Command + "'"
+			if c.Request.Header.RequestId != "" {
+				label += " request_id=" + c.Request.Header.RequestId
+			}
+			if c.Request.Header.Traceparent != "" {
+				label += " traceparent=" + c.Request.Header.Traceparent
+			}
+
+			if reply.IsOK() {
+				log.Printf("%s replied with OK", label)
+			} else {
+				log.Printf("%s replied with a failure: %s", label, reply.Message)
+			}
+			return reply
+		}
+	}
+}
+
+// Prints the time that the command handler took
+func Timing() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *HandlerContext) message.Reply {
+			start := time.Now()
+			reply := next(c)
+			log.Printf("command '%s' took %s", c.Request.Command, time.Since(start))
+			return reply
+		}
+	}
+}
+
+// Converts the panic in the command handler into a failure reply.
+// Without it the panic stops the controller.
+func Recovery() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *HandlerContext) (reply message.Reply) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("command '%s' panicked: %v", c.Request.Command, r)
+					reply = message.FailWithCode(message.INTERNAL, fmt.Sprintf("internal error of the '%s' command", c.Request.Command))
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// Calls the command handler only if the authorize function didn't return an error.
+// Otherwise the error is returned as a failure reply.
+//
+// Set it as the command's own middleware, since the global middlewares are called before the authentication.
+func Auth(authorize func(*HandlerContext) error) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *HandlerContext) message.Reply {
+			if err := authorize(c); err != nil {
+				return message.FailWithCode(message.UNAUTHORIZED, "unauthorized: "+err.Error())
+			}
+			return next(c)
+		}
+	}
+}
+
+// Allows at most the limit requests within the interval.
+// The requests over the limit are replied with a failure.
+//
+// If the middleware is set globally, then the limit is shared by all commands.
+func RateLimit(limit uint, interval time.Duration) Middleware {
+	var mu sync.Mutex
+	window_start := time.Now()
+	var amount uint = 0
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *HandlerContext) message.Reply {
+			mu.Lock()
+			now := time.Now()
+			if now.Sub(window_start) >= interval {
+				window_start = now
+				amount = 0
+			}
+			amount++
+			exceeded := amount > limit
+			mu.Unlock()
+
+			if exceeded {
+				return message.FailWithCode(message.RATE_LIMITED, "rate limit exceeded for the '"+c.Request.Command+"' command")
+			}
+			return next(c)
+		}
+	}
+}
@@ -5,6 +5,7 @@ It acts as the input receiver for other services or for external users.
 package controller
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -16,63 +17,68 @@ import (
 	zmq "github.com/pebbe/zmq4"
 )
 
+// The command handlers by the command name.
+// The handler should be one of the function signatures supported by Router.Register().
 type CommandHandlers map[string]interface{}
 
 // Creates a new Reply controller using ZeroMQ
 // The requesters is the list of curve public keys that are allowed to connect to the socket.
+//
+// The command handlers are validated before the controller starts.
 func ReplyController(db *sql.DB, commands CommandHandlers, e *env.Env, accounts account.Accounts) error {
-	if !e.PortExist() {
-		return errors.New("missing necessary environment variables. Please set '" + e.ServiceName() + "_PORT' and/or '" + e.ServiceName() + "_PUBLIC_KEY', '" + e.ServiceName() + "_SECRET_KEY'")
-	}
+	return ReplyControllerContext(context.Background(), db, commands, e, accounts)
+}
 
-	exist, err := argument.Exist(argument.PLAIN)
+// Same as ReplyController(), but the controller stops when the context is done.
+func ReplyControllerContext(ctx context.Context, db *sql.DB, commands CommandHandlers, e *env.Env, accounts account.Accounts) error {
+	router, err := NewRouterFromHandlers(commands)
 	if err != nil {
 		return err
 	}
 
-	if !exist {
-		// only whitelisted users are allowed
-		zmq.AuthCurveAdd("*", accounts.PublicKeys()...)
-	}
+	return RouterControllerContext(ctx, db, router, e, accounts)
+}
 
-	// Socket to talk to clients
-	socket, err := zmq.NewSocket(zmq.REP)
+// Creates a new Reply controller using ZeroMQ that handles the commands by the router.
+// The requesters is the list of curve public keys that are allowed to connect to the socket.
+func RouterController(db *sql.DB, router *Router, e *env.Env, accounts account.Accounts) error {
+	return RouterControllerContext(context.Background(), db, router, e, accounts)
+}
+
+// Same as RouterController(), but the controller stops when the context is done.
+// The socket is closed and nil is returned after the stop.
+func RouterControllerContext(ctx context.Context, db *sql.DB, router *Router, e *env.Env, accounts account.Accounts) error {
+	socket, err := new_server_socket(zmq.REP, e, accounts)
 	if err != nil {
 		return err
-	} else {
-		defer socket.Close()
-	}
-
-	if !exist {
-		err = socket.ServerAuthCurve(e.DomainName(), e.SecretKey())
-		if err != nil {
-			return err
-		}
-	}
-
-	if err := socket.Bind("tcp://*:" + e.Port()); err != nil {
-		return errors.New("error to bind socket for '" + e.ServiceName() + " - " + e.Url() + "' : " + err.Error())
 	}
+	defer socket.Close()
 
 	println("'" + e.ServiceName() + "' request-reply server runs on port " + e.Port())
 
+	poller := zmq.NewPoller()
+	poller.Add(socket, zmq.POLLIN)
+
 	for {
-		// msg_raw, metadata, err := socket.RecvMessageWithMetadata(0, "pub_key")
-		msg_raw, err := socket.RecvMessage(0)
+		// The REP socket replied to the previous request,
+		// so nothing is left in-flight when we stop.
+		if ctx.Err() != nil {
+			println("'" + e.ServiceName() + "' request-reply server stopped")
+			return nil
+		}
+
+		sockets, err := poller.Poll(worker_poll_interval)
 		if err != nil {
-			fail := message.Fail("socket error to receive message " + err.Error())
-			reply := fail.ToString()
-			if _, err := socket.SendMessage(reply); err != nil {
-				return errors.New("failed to reply: %w" + err.Error())
-			}
+			return err
+		}
+		if len(sockets) == 0 {
 			continue
 		}
 
-		// All request types derive from the basic request.
-		// We first attempt to parse basic request from the raw message
-		request, err := message.ParseRequest(msg_raw)
+		// msg_raw, metadata, err := socket.RecvMessageWithMetadata(0, "pub_key")
+		msg_raw, err := socket.RecvMessage(0)
 		if err != nil {
-			fail := message.Fail("invalid json request: " + err.Error())
+			fail := message.FailWithCode(message.INTERNAL, "socket error to receive message "+err.Error())
 			reply := fail.ToString()
 			if _, err := socket.SendMessage(reply); err != nil {
 				return errors.New("failed to reply: %w" + err.Error())
@@ -80,68 +86,60 @@ func ReplyController(db *sql.DB, commands CommandHandlers, e *env.Env, accounts
 			continue
 		}
 
-		// Any request types is compatible with the Request.
-		if commands[request.Command] == nil {
-			fail := message.Fail("unsupported command " + request.Command)
-			reply := fail.ToString()
-			if _, err := socket.SendMessage(reply); err != nil {
-				return errors.New("failed to reply: %w" + err.Error())
-			}
-			continue
+		reply := router.DispatchContext(ctx, db, msg_raw)
+
+		if _, err := socket.SendMessage(encode_reply(msg_raw, reply)); err != nil {
+			return errors.New("failed to reply: %w" + err.Error())
 		}
+	}
+}
 
-		var reply message.Reply
-
-		// The command might be from a smartcontract developer.
-		command_handler, ok := commands[request.Command].(func(*sql.DB, message.SmartcontractDeveloperRequest, *account.SmartcontractDeveloper) message.Reply)
-		if ok {
-			smartcontract_developer_request, err := message.ParseSmartcontractDeveloperRequest(msg_raw)
-			if err != nil {
-				fail := message.Fail("invalid smartcontract developer request " + err.Error())
-				reply := fail.ToString()
-				if _, err := socket.SendMessage(reply); err != nil {
-					return errors.New("failed to reply: %w" + err.Error())
-				}
-				continue
-			}
+// Encodes the reply by the codec of the request.
+// If the reply can't be encoded by it, then the failure is returned as JSON.
+func encode_reply(msg_raw []string, reply message.Reply) []string {
+	frames, err := message.EncodeFrames(message.FramesCodec(msg_raw), reply.ToJSON())
+	if err != nil {
+		fail := message.FailWithCode(message.INTERNAL, "failed to encode the reply: "+err.Error())
+		return []string{fail.ToString()}
+	}
 
-			smartcontract_developer, err := account.NewSmartcontractDeveloper(&smartcontract_developer_request)
-			if err != nil {
-				println(smartcontract_developer_request.NonceTimestamp)
-				fail := message.Fail("reply controller error as invalid smartcontract developer request: " + err.Error())
-				reply := fail.ToString()
-				if _, err := socket.SendMessage(reply); err != nil {
-					return errors.New("failed to reply: %w" + err.Error())
-				}
-				continue
-			}
+	return frames
+}
 
-			reply = command_handler(db, smartcontract_developer_request, smartcontract_developer)
-		} else {
-			// The command might be from another SDS Service
-			service_handler, ok := commands[request.Command].(func(*sql.DB, message.ServiceRequest, *account.Account) message.Reply)
-			if ok {
-				service_request, err := message.ParseServiceRequest(msg_raw)
-				if err != nil {
-					fail := message.Fail("invalid service request " + err.Error())
-					reply := fail.ToString()
-					if _, err := socket.SendMessage(reply); err != nil {
-						return errors.New("failed to reply: %w" + err.Error())
-					}
-					continue
-				}
-
-				service_account := account.NewService(service_request.Service)
-
-				reply = service_handler(db, service_request, service_account)
-			} else {
-				// The command is from a developer.
-				reply = commands[request.Command].(func(*sql.DB, message.Request) message.Reply)(db, request)
-			}
-		}
+// Creates a socket to talk to clients, bound to the request-reply port of the service.
+// Unless the service runs in plain mode, only whitelisted accounts are allowed to connect.
+func new_server_socket(socket_type zmq.Type, e *env.Env, accounts account.Accounts) (*zmq.Socket, error) {
+	if !e.PortExist() {
+		return nil, errors.New("missing necessary environment variables. Please set '" + e.ServiceName() + "_PORT' and/or '" + e.ServiceName() + "_PUBLIC_KEY', '" + e.ServiceName() + "_SECRET_KEY'")
+	}
 
-		if _, err := socket.SendMessage(reply.ToString()); err != nil {
-			return errors.New("failed to reply: %w" + err.Error())
+	exist, err := argument.Exist(argument.PLAIN)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		// only whitelisted users are allowed
+		zmq.AuthCurveAdd("*", accounts.PublicKeys()...)
+	}
+
+	socket, err := zmq.NewSocket(socket_type)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		err = socket.ServerAuthCurve(e.DomainName(), e.SecretKey())
+		if err != nil {
+			socket.Close()
+			return nil, err
 		}
 	}
+
+	if err := socket.Bind("tcp://*:" + e.Port()); err != nil {
+		socket.Close()
+		return nil, errors.New("error to bind socket for '" + e.ServiceName() + " - " + e.Url() + "' : " + err.Error())
+	}
+
+	return socket, nil
 }
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/blocklords/gosds/account"
+	"github.com/blocklords/gosds/message"
+)
+
+// The kind of the requester that the command handler expects.
+// Depending on the kind, the router parses and authenticates the request.
+const (
+	DEVELOPER_REQUEST               uint8 = 1 // message.Request
+	SERVICE_REQUEST                 uint8 = 2 // message.ServiceRequest
+	SMARTCONTRACT_DEVELOPER_REQUEST uint8 = 3 // message.SmartcontractDeveloperRequest
+)
+
+// The data passed to the command handler.
+//
+// Depending on the kind of the command, either ServiceRequest with Account
+// or SmartcontractDeveloperRequest with SmartcontractDeveloper are set.
+type HandlerContext struct {
+	Context context.Context // cancelled when the handler runs out of time
+	DB      *sql.DB
+	Raw     []string        // the raw message as it was received from the socket
+	Request message.Request // every request type is compatible with the basic request
+
+	ServiceRequest *message.ServiceRequest
+	Account        *account.Account // the authenticated SDS Service
+
+	SmartcontractDeveloperRequest *message.SmartcontractDeveloperRequest
+	SmartcontractDeveloper        *account.SmartcontractDeveloper // the authenticated smartcontract developer
+}
+
+// The command handler
+type HandlerFunc func(*HandlerContext) message.Reply
+
+// The Middleware wraps the handler, to do something before or after the handler.
+type Middleware func(HandlerFunc) HandlerFunc
+
+type route struct {
+	kind    uint8
+	handler HandlerFunc
+	schema  Schema // optional
+}
+
+// The Router keeps the command handlers of the controller.
+type Router struct {
+	routes         map[string]*route
+	middlewares    []Middleware
+	chain          HandlerFunc            // the global middlewares around route_handler()
+	nonce_verifier *account.NonceVerifier // nil if the nonces are not checked
+}
+
+// Creates an empty router.
+// The nonces of the SmartcontractDeveloperRequest are not checked, unless SetNonceVerifier() is called.
+func NewRouter() *Router {
+	router := &Router{
+		routes:      map[string]*route{},
+		middlewares: []Middleware{},
+	}
+	router.chain = router.route_handler
+
+	return router
+}
+
+// Creates a router from the command handlers.
+// Returns an error if one of the handlers has an unsupported signature.
+func NewRouterFromHandlers(commands CommandHandlers) (*Router, error) {
+	router := NewRouter()
+	for command, handler := range commands {
+		if err := router.Register(command, handler); err != nil {
+			return nil, err
+		}
+	}
+
+	return router, nil
+}
+
+// Add the middlewares that are applied to all requests.
+//
+// The global middlewares are called in the order they were added, around the whole dispatch:
+// the command lookup, the authentication, the nonce and the parameters validation,
+// then the command's own middlewares and the handler.
+// Therefore they see the rejected and unauthenticated requests too, and
+// the authenticated fields of the HandlerContext are not set yet when they are called.
+// Use Auth() as the command's own middleware.
+func (router *Router) Use(middlewares ...Middleware) {
+	router.middlewares = append(router.middlewares, middlewares...)
+
+	handler := router.route_handler
+	for i := len(router.middlewares) - 1; i >= 0; i-- {
+		handler = router.middlewares[i](handler)
+	}
+	router.chain = handler
+}
+
+// Register the handler of the command sent by a developer as message.Request.
+func (router *Router) Handle(command string, handler HandlerFunc, middlewares ...Middleware) error {
+	return router.add(command, DEVELOPER_REQUEST, handler, middlewares)
+}
+
+// Register the handler of the command sent by another SDS Service as message.ServiceRequest.
+// The handler context will have ServiceRequest and Account.
+func (router *Router) HandleService(command string, handler HandlerFunc, middlewares ...Middleware) error {
+	return router.add(command, SERVICE_REQUEST, handler, middlewares)
+}
+
+// Register the handler of the command sent by a smartcontract developer as message.SmartcontractDeveloperRequest.
+// The handler context will have SmartcontractDeveloperRequest and SmartcontractDeveloper.
+func (router *Router) HandleSmartcontractDeveloper(command string, handler HandlerFunc, middlewares ...Middleware) error {
+	return router.add(command, SMARTCONTRACT_DEVELOPER_REQUEST, handler, middlewares)
+}
+
+// Register the command handler defined as one of the CommandHandlers function signatures:
+//
+//	func(*sql.DB, message.Request) message.Reply
+//	func(*sql.DB, message.ServiceRequest, *account.Account) message.Reply
+//	func(*sql.DB, message.SmartcontractDeveloperRequest, *account.SmartcontractDeveloper) message.Reply
+//
+// Any other signature is rejected.
+func (router *Router) Register(command string, handler interface{}, middlewares ...Middleware) error {
+	switch f := handler.(type) {
+	case HandlerFunc:
+		return router.Handle(command, f, middlewares...)
+	case func(*HandlerContext) message.Reply:
+		return router.Handle(command, f, middlewares...)
+	case func(*sql.DB, message.Request) message.Reply:
+		return router.Handle(command, func(c *HandlerContext) message.Reply {
+			return f(c.DB, c.Request)
+		}, middlewares...)
+	case func(*sql.DB, message.ServiceRequest, *account.Account) message.Reply:
+		return router.HandleService(command, func(c *HandlerContext) message.Reply {
+			return f(c.DB, *c.ServiceRequest, c.Account)
+		}, middlewares...)
+	case func(*sql.DB, message.SmartcontractDeveloperRequest, *account.SmartcontractDeveloper) message.Reply:
+		return router.HandleSmartcontractDeveloper(command, func(c *HandlerContext) message.Reply {
+			return f(c.DB, *c.SmartcontractDeveloperRequest, c.SmartcontractDeveloper)
+		}, middlewares...)
+	default:
+		return fmt.Errorf("the '%s' command handler has unsupported signature %T", command, handler)
+	}
+}
+
+// Whether the router has a handler for the command
+func (router *Router) Has(command string) bool {
+	_, ok := router.routes[command]
+	return ok
+}
+
+// The list of registered commands
+func (router *Router) Commands() []string {
+	commands := make([]string, 0, len(router.routes))
+	for command := range router.routes {
+		commands = append(commands, command)
+	}
+
+	return commands
+}
+
+// Set the verifier of the SmartcontractDeveloperRequest nonces.
+// The requests with the stale or reused nonce are replied with the message.INVALID_NONCE failure.
+//
+// The replay protection is opt-in, since the clients that sign several requests
+// in the same second with the same nonce would be rejected:
+//
+//	router.SetNonceVerifier(account.NewNonceVerifier(account.NewMemoryNonceStore(), 0))
+//
+// If the service runs in several instances, set the verifier with db.NonceStore to share the nonces.
+// The nil verifier switches off the replay protection.
+func (router *Router) SetNonceVerifier(verifier *account.NonceVerifier) {
+	router.nonce_verifier = verifier
+}
+
+// Set the parameters schema of the registered command.
+// The request parameters are validated against it after the authentication,
+// before the middlewares and the handler are called.
+func (router *Router) SetSchema(command string, schema Schema) error {
+	route, ok := router.routes[command]
+	if !ok {
+		return errors.New("the '" + command + "' command handler is not registered")
+	}
+	route.schema = schema
+	return nil
+}
+
+// Returns the parameters schema of the command, or nil if it's not set.
+func (router *Router) Schema(command string) Schema {
+	route, ok := router.routes[command]
+	if !ok {
+		return nil
+	}
+	return route.schema
+}
+
+// The JSON Schema of all commands that have the schema.
+// The "commands" maps the command name to the JSON Schema of its parameters.
+func (router *Router) JsonSchema() map[string]interface{} {
+	commands := map[string]interface{}{}
+	for command, route := range router.routes {
+		if route.schema != nil {
+			commands[command] = route.schema.ToJsonSchema()
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":  "http://json-schema.org/draft-07/schema#",
+		"commands": commands,
+	}
+}
+
+func (router *Router) add(command string, kind uint8, handler HandlerFunc, middlewares []Middleware) error {
+	if len(command) == 0 {
+		return errors.New("the command name is empty")
+	}
+	if handler == nil {
+		return errors.New("the '" + command + "' command handler is nil")
+	}
+	if router.Has(command) {
+		return errors.New("the '" + command + "' command handler is already registered")
+	}
+
+	// the first middleware is the outer one
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	router.routes[command] = &route{kind: kind, handler: handler}
+
+	return nil
+}
+
+// Parse the raw message, authenticate the requester, then call the command handler.
+// The errors are returned as a failure reply.
+func (router *Router) Dispatch(db *sql.DB, msg_raw []string) message.Reply {
+	return router.DispatchContext(context.Background(), db, msg_raw)
+}
+
+// Same as Dispatch(), but the context is passed to the command handler.
+//
+// The header of the request is echoed back in the reply.
+// The handler's context carries the header, see message.HeaderFromContext(), and
+// is cancelled at the header's deadline.
+func (router *Router) DispatchContext(ctx context.Context, db *sql.DB, msg_raw []string) message.Reply {
+	// All request types derive from the basic request.
+	// We first attempt to parse basic request from the raw message
+	request, err := message.ParseRequest(msg_raw)
+	if err != nil {
+		return message.FailWithCode(message.INVALID_PARAMS, "invalid json request: "+err.Error())
+	}
+
+	if request.Header.HasDeadline() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, request.Header.Deadline)
+		defer cancel()
+	}
+	ctx = message.ContextWithHeader(ctx, request.Header)
+
+	reply := router.dispatch(ctx, db, msg_raw, request)
+	reply.Header = request.Header
+
+	return reply
+}
+
+// Calls the global middlewares with the route_handler().
+func (router *Router) dispatch(ctx context.Context, db *sql.DB, msg_raw []string, request message.Request) message.Reply {
+	handler_context := HandlerContext{
+		Context: ctx,
+		DB:      db,
+		Raw:     msg_raw,
+		Request: request,
+	}
+
+	return router.chain(&handler_context)
+}
+
+// Authenticates the requester, validates the parameters, then calls the command handler.
+func (router *Router) route_handler(handler_context *HandlerContext) message.Reply {
+	request := handler_context.Request
+	msg_raw := handler_context.Raw
+
+	route, ok := router.routes[request.Command]
+	if !ok {
+		return message.FailWithCode(message.UNSUPPORTED_COMMAND, "unsupported command "+request.Command)
+	}
+
+	switch route.kind {
+	case SMARTCONTRACT_DEVELOPER_REQUEST:
+		smartcontract_developer_request, err := message.ParseSmartcontractDeveloperRequest(msg_raw)
+		if err != nil {
+			return message.FailWithCode(message.INVALID_PARAMS, "invalid smartcontract developer request "+err.Error())
+		}
+
+		smartcontract_developer, err := account.NewSmartcontractDeveloper(&smartcontract_developer_request)
+		if err != nil {
+			return message.FailWithCode(message.UNAUTHORIZED, "reply controller error as invalid smartcontract developer request: "+err.Error())
+		}
+
+		// after the signature, so that only the owner of the address could use its nonce
+		if router.nonce_verifier != nil {
+			if err := router.nonce_verifier.Verify(&smartcontract_developer_request); err != nil {
+				return message.FailWithCode(message.INVALID_NONCE, "invalid nonce: "+err.Error())
+			}
+		}
+
+		handler_context.SmartcontractDeveloperRequest = &smartcontract_developer_request
+		handler_context.SmartcontractDeveloper = smartcontract_developer
+	case SERVICE_REQUEST:
+		service_request, err := message.ParseServiceRequest(msg_raw)
+		if err != nil {
+			return message.FailWithCode(message.UNAUTHORIZED, "invalid service request "+err.Error())
+		}
+
+		handler_context.ServiceRequest = &service_request
+		handler_context.Account = account.NewService(service_request.Service)
+	}
+
+	if route.schema != nil {
+		if err := route.schema.Validate(request.Parameters); err != nil {
+			return message.InvalidParams(err)
+		}
+	}
+
+	return route.handler(handler_context)
+}
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+
+	"github.com/blocklords/gosds/message"
+	"github.com/blocklords/gosds/topic"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The JSON type of the parameter
+type ParamType string
+
+const (
+	STRING  ParamType = "string"
+	INTEGER ParamType = "integer"
+	NUMBER  ParamType = "number"
+	BOOLEAN ParamType = "boolean"
+	OBJECT  ParamType = "object"
+	ARRAY   ParamType = "array"
+	ANY     ParamType = "" // any type is accepted
+)
+
+// The format of the string parameter
+const (
+	TOPIC_STRING_FORMAT = "topic_string" // parsed by topic.ParseString()
+	ADDRESS_FORMAT      = "address"      // the hex address of the blockchain account or smartcontract
+)
+
+// The inclusive range of the number parameter.
+// The nil bound is not checked, for example the range with only Min has no upper limit.
+type Range struct {
+	Min *float64
+	Max *float64
+}
+
+// The description of the command parameter.
+type Param struct {
+	Name        string
+	Type        ParamType
+	Optional    bool
+	Description string
+	Range       *Range // optional, for the integer and number parameters
+	Format      string // optional, for the string parameters
+	Items       *Param // optional, the elements of the array parameter. The name is not used.
+	Properties  Schema // optional, the fields of the object parameter
+}
+
+// The parameters that the command accepts.
+//
+// Set it for the command by Router.SetSchema(), then the router
+// replies with the message.INVALID_PARAMS failure, if the request parameters don't match it.
+//
+//	router.SetSchema("smartcontract_read", controller.Schema{
+//		{Name: "topic_string", Type: controller.STRING, Format: controller.TOPIC_STRING_FORMAT},
+//		{Name: "arguments", Type: controller.OBJECT},
+//		{Name: "address", Type: controller.STRING, Format: controller.ADDRESS_FORMAT},
+//	})
+//
+// The parameters that are not listed in the schema are allowed.
+type Schema []Param
+
+// Checks the parameters against the schema.
+// All invalid parameters are returned in the *message.ParamsError.
+func (schema Schema) Validate(parameters map[string]interface{}) error {
+	params_error := &message.ParamsError{}
+	schema.validate(parameters, "", params_error)
+	if len(params_error.Fields) > 0 {
+		return params_error
+	}
+	return nil
+}
+
+func (schema Schema) validate(parameters map[string]interface{}, path string, params_error *message.ParamsError) {
+	for _, param := range schema {
+		param_path := param.Name
+		if path != "" {
+			param_path = path + "." + param.Name
+		}
+
+		raw, exists := parameters[param.Name]
+		if !exists || raw == nil {
+			if !param.Optional {
+				params_error.Fields = append(params_error.Fields, message.FieldError{Path: param_path, Message: "parameter is missing"})
+			}
+			continue
+		}
+
+		param.validate(raw, param_path, params_error)
+	}
+}
+
+func (param *Param) validate(raw interface{}, path string, params_error *message.ParamsError) {
+	fail := func(reason string) {
+		params_error.Fields = append(params_error.Fields, message.FieldError{Path: path, Message: reason})
+	}
+
+	switch param.Type {
+	case STRING:
+		value, ok := raw.(string)
+		if !ok {
+			fail("expected to be a string")
+			return
+		}
+		switch param.Format {
+		case TOPIC_STRING_FORMAT:
+			if _, err := topic.ParseString(value); err != nil {
+				fail("invalid topic string: " + err.Error())
+			}
+		case ADDRESS_FORMAT:
+			if !common.IsHexAddress(value) {
+				fail("invalid address '" + value + "'")
+			}
+		}
+	case INTEGER, NUMBER:
+		if !is_number(raw) {
+			fail("expected to be a number")
+			return
+		}
+		value, err := message.GetDecimal(map[string]interface{}{"value": raw}, "value")
+		if err != nil {
+			fail("expected to be a number")
+			return
+		}
+		if param.Type == INTEGER && !value.IsInt() {
+			fail("expected to be an integer")
+			return
+		}
+		if param.Range != nil && param.Range.Min != nil {
+			min := new(big.Rat).SetFloat64(*param.Range.Min)
+			if min == nil || value.Cmp(min) < 0 {
+				fail("expected to be at least " + strconv.FormatFloat(*param.Range.Min, 'f', -1, 64))
+				return
+			}
+		}
+		if param.Range != nil && param.Range.Max != nil {
+			max := new(big.Rat).SetFloat64(*param.Range.Max)
+			if max == nil || value.Cmp(max) > 0 {
+				fail("expected to be at most " + strconv.FormatFloat(*param.Range.Max, 'f', -1, 64))
+			}
+		}
+	case BOOLEAN:
+		if _, ok := raw.(bool); !ok {
+			fail("expected to be a boolean")
+		}
+	case OBJECT:
+		value, ok := raw.(map[string]interface{})
+		if !ok {
+			fail("expected to be an object")
+			return
+		}
+		param.Properties.validate(value, path, params_error)
+	case ARRAY:
+		values, ok := to_list(raw)
+		if !ok {
+			fail("expected to be an array")
+			return
+		}
+		if param.Items != nil {
+			for i, value := range values {
+				param.Items.validate(value, path+"["+strconv.Itoa(i)+"]", params_error)
+			}
+		}
+	}
+}
+
+// Whether the decoded value is the JSON number
+func is_number(raw interface{}) bool {
+	switch raw.(type) {
+	case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// The decoded JSON array. The []string is created by the Go clients.
+func to_list(raw interface{}) ([]interface{}, bool) {
+	switch values := raw.(type) {
+	case []interface{}:
+		return values, true
+	case []string:
+		list := make([]interface{}, len(values))
+		for i, value := range values {
+			list[i] = value
+		}
+		return list, true
+	case []map[string]interface{}:
+		list := make([]interface{}, len(values))
+		for i, value := range values {
+			list[i] = value
+		}
+		return list, true
+	default:
+		return nil, false
+	}
+}
+
+// The JSON Schema (draft-07) of the command parameters.
+// The SDK clients in other languages could validate the request before sending it.
+func (schema Schema) ToJsonSchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []interface{}{}
+	for _, param := range schema {
+		properties[param.Name] = param.ToJsonSchema()
+		if !param.Optional {
+			required = append(required, param.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       string(OBJECT),
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// The JSON Schema of the parameter.
+// The formats are exported as the patterns, since JSON Schema doesn't know them.
+func (param *Param) ToJsonSchema() map[string]interface{} {
+	if param.Type == OBJECT {
+		object := param.Properties.ToJsonSchema()
+		if param.Description != "" {
+			object["description"] = param.Description
+		}
+		return object
+	}
+
+	i := map[string]interface{}{}
+	if param.Type != ANY {
+		i["type"] = string(param.Type)
+	}
+	if param.Description != "" {
+		i["description"] = param.Description
+	}
+	if param.Range != nil && param.Range.Min != nil {
+		i["minimum"] = *param.Range.Min
+	}
+	if param.Range != nil && param.Range.Max != nil {
+		i["maximum"] = *param.Range.Max
+	}
+	switch param.Format {
+	case TOPIC_STRING_FORMAT:
+		i["format"] = TOPIC_STRING_FORMAT
+		i["pattern"] = `^[a-z]:[^;:]+(;[a-z]:[^;:]+){1,6}$`
+	case ADDRESS_FORMAT:
+		i["format"] = ADDRESS_FORMAT
+		i["pattern"] = `^(0x|0X)?[0-9a-fA-F]{40}$`
+	}
+	if param.Type == ARRAY && param.Items != nil {
+		i["items"] = param.Items.ToJsonSchema()
+	}
+
+	return i
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"regexp"
+	"testing"
+)
+
+// The pattern of the JSON Schema should accept the same topic strings as Validate().
+func TestTopicStringPattern(t *testing.T) {
+	schema := Schema{{Name: "topic_string", Type: STRING, Format: TOPIC_STRING_FORMAT}}
+	pattern := regexp.MustCompile(schema[0].ToJsonSchema()["pattern"].(string))
+
+	vectors := []struct {
+		topic_string string
+		valid        bool
+	}{
+		{"o:seascape;p:blocklords", true},
+		{"o:seascape;p:blocklords;n:1;g:nft;s:Hero;m:mint", true},
+		{"o:seascape;p:blocklords;n:1;g:nft;s:Hero;e:Transfer", true},
+		{"o:seascape;p:blocklords;n:1;g:nft;s:Hero;v:2;m:mint", true},
+		{"o:seascape;p:blocklords;n:1;g:nft;s:Hero;v:2;e:Transfer", true},
+		{"o:seascape", false},
+		{"o:seascape;p:blocklords;n:1;g:nft;s:Hero;v:2;m:mint;e:Transfer", false},
+	}
+
+	for _, vector := range vectors {
+		if matched := pattern.MatchString(vector.topic_string); matched != vector.valid {
+			t.Errorf("the pattern matched %s: %v", vector.topic_string, matched)
+		}
+		if !vector.valid {
+			continue
+		}
+		if err := schema.Validate(map[string]interface{}{"topic_string": vector.topic_string}); err != nil {
+			t.Errorf("%s: %v", vector.topic_string, err)
+		}
+	}
+}
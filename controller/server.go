@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/blocklords/gosds/account"
+	"github.com/blocklords/gosds/env"
+)
+
+// Returned by Server.Start() if the server is already running
+var ErrServerRunning = errors.New("the server is already running")
+
+// The Server runs the reply controller in the background,
+// so that the host process could stop and restart it.
+type Server struct {
+	db       *sql.DB
+	router   *Router
+	e        *env.Env
+	accounts account.Accounts
+	workers  *WorkerOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Creates a server that handles one request at a time.
+func NewServer(db *sql.DB, router *Router, e *env.Env, accounts account.Accounts) *Server {
+	return &Server{
+		db:       db,
+		router:   router,
+		e:        e,
+		accounts: accounts,
+	}
+}
+
+// Creates a server that handles the requests in parallel by the workers.
+// See WorkerController()
+func NewWorkerServer(db *sql.DB, router *Router, e *env.Env, accounts account.Accounts, options WorkerOptions) *Server {
+	server := NewServer(db, router, e, accounts)
+	server.workers = &options
+	return server
+}
+
+// Start the controller in the background.
+//
+// The returned channel receives the error if the controller stopped because of it.
+// The channel is closed once the controller stops.
+// The controller stops when the context is done or Stop() is called.
+func (server *Server) Start(ctx context.Context) <-chan error {
+	error_channel := make(chan error, 1)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if server.done != nil {
+		error_channel <- ErrServerRunning
+		close(error_channel)
+		return error_channel
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	server.cancel = cancel
+	server.done = done
+
+	go func() {
+		var err error
+		if server.workers != nil {
+			err = WorkerController(ctx, server.db, server.router, server.e, server.accounts, *server.workers)
+		} else {
+			err = RouterControllerContext(ctx, server.db, server.router, server.e, server.accounts)
+		}
+		cancel()
+
+		server.mu.Lock()
+		server.cancel = nil
+		server.done = nil
+		server.mu.Unlock()
+
+		if err != nil {
+			error_channel <- err
+		}
+		close(error_channel)
+		close(done)
+	}()
+
+	return error_channel
+}
+
+// Stops the controller and waits until it closes the socket.
+// After that the server could be started again.
+func (server *Server) Stop() {
+	server.mu.Lock()
+	cancel := server.cancel
+	done := server.done
+	server.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blocklords/gosds/account"
+	"github.com/blocklords/gosds/env"
+	"github.com/blocklords/gosds/message"
+
+	zmq "github.com/pebbe/zmq4"
+)
+
+// How often the broker and the workers check whether they should stop.
+const worker_poll_interval = 100 * time.Millisecond
+
+// The parameters of the WorkerController
+type WorkerOptions struct {
+	Workers        int           // amount of the requests handled in parallel, including the timed out handlers that still run
+	HandlerTimeout time.Duration // if the handler didn't reply in this time, the fail is replied. 0 means no timeout
+	DrainTimeout   time.Duration // on shutdown, how long to wait for the in-flight requests. 0 means wait until they are replied
+}
+
+// The default worker options
+func DefaultWorkerOptions() WorkerOptions {
+	return WorkerOptions{
+		Workers:        4,
+		HandlerTimeout: 30 * time.Second,
+		DrainTimeout:   10 * time.Second,
+	}
+}
+
+// Creates a new Reply controller that handles many requests in parallel.
+//
+// It follows the extended request-reply pattern of ZeroMQ:
+// the ROUTER socket accepts the requests from clients, then passes them
+// over the DEALER socket to the worker goroutines. Each worker has its own REP socket.
+//
+// The controller runs until the context is cancelled.
+// Then it stops accepting new requests, waits for the in-flight requests to be replied
+// and closes the sockets.
+func WorkerController(ctx context.Context, db *sql.DB, router *Router, e *env.Env, accounts account.Accounts, options WorkerOptions) error {
+	if options.Workers < 1 {
+		return errors.New("the worker controller requires atleast one worker")
+	}
+
+	frontend, err := new_server_socket(zmq.ROUTER, e, accounts)
+	if err != nil {
+		return err
+	}
+	defer frontend.Close()
+
+	backend, err := zmq.NewSocket(zmq.DEALER)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	backend_url := "inproc://" + e.DomainName() + "_workers"
+	if err := backend.Bind(backend_url); err != nil {
+		return fmt.Errorf("error to bind the workers socket for '%s': %w", e.ServiceName(), err)
+	}
+
+	workers_stop := make(chan struct{})
+	// the slots of the running handlers, shared by the workers.
+	// the timed out handler keeps its slot until it returns.
+	handlers := make(chan struct{}, options.Workers)
+	var workers sync.WaitGroup
+	worker_errors := make(chan error, options.Workers)
+
+	for i := 0; i < options.Workers; i++ {
+		worker, err := zmq.NewSocket(zmq.REP)
+		if err != nil {
+			close(workers_stop)
+			workers.Wait()
+			return err
+		}
+		if err := worker.Connect(backend_url); err != nil {
+			worker.Close()
+			close(workers_stop)
+			workers.Wait()
+			return err
+		}
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			defer worker.Close()
+			if err := run_worker(worker, db, router, options.HandlerTimeout, handlers, workers_stop); err != nil {
+				worker_errors <- err
+			}
+		}()
+	}
+
+	println("'" + e.ServiceName() + "' request-reply server runs on port " + e.Port() + " with " + fmt.Sprint(options.Workers) + " workers")
+
+	broker_err := run_broker(ctx, frontend, backend, options.DrainTimeout, worker_errors)
+
+	close(workers_stop)
+	workers.Wait()
+
+	return broker_err
+}
+
+// Passes the requests from the frontend to the backend and the replies back.
+// After the context is done, only the replies are passed until there are no in-flight requests.
+func run_broker(ctx context.Context, frontend *zmq.Socket, backend *zmq.Socket, drain_timeout time.Duration, worker_errors chan error) error {
+	poller := zmq.NewPoller()
+	frontend_id := poller.Add(frontend, zmq.POLLIN)
+	poller.Add(backend, zmq.POLLIN)
+
+	in_flight := 0
+	draining := false
+	var drain_deadline time.Time
+
+	for {
+		select {
+		case err := <-worker_errors:
+			return err
+		default:
+		}
+
+		if !draining && ctx.Err() != nil {
+			draining = true
+			drain_deadline = time.Now().Add(drain_timeout)
+			// stop accepting new requests
+			if err := poller.Remove(frontend_id); err != nil {
+				return err
+			}
+		}
+		if draining {
+			if in_flight <= 0 {
+				return nil
+			}
+			if drain_timeout > 0 && time.Now().After(drain_deadline) {
+				return fmt.Errorf("%d in-flight requests weren't replied before the drain timeout", in_flight)
+			}
+		}
+
+		sockets, err := poller.Poll(worker_poll_interval)
+		if err != nil {
+			return err
+		}
+
+		for _, polled := range sockets {
+			switch polled.Socket {
+			case frontend:
+				msg, err := frontend.RecvMessageBytes(0)
+				if err != nil {
+					return err
+				}
+				if _, err := backend.SendMessage(msg); err != nil {
+					return err
+				}
+				in_flight++
+			case backend:
+				msg, err := backend.RecvMessageBytes(0)
+				if err != nil {
+					return err
+				}
+				if _, err := frontend.SendMessage(msg); err != nil {
+					return err
+				}
+				in_flight--
+			}
+		}
+	}
+}
+
+// The worker receives the request from the broker, handles it and replies back.
+//
+// The worker accepts the request only if there is a free slot in the handlers.
+// The slot is released when the handler returns, even if the worker already replied with the timeout.
+// Therefore the amount of the running handlers never exceeds the capacity of the handlers.
+func run_worker(worker *zmq.Socket, db *sql.DB, router *Router, handler_timeout time.Duration, handlers chan struct{}, stop chan struct{}) error {
+	poller := zmq.NewPoller()
+	poller.Add(worker, zmq.POLLIN)
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case handlers <- struct{}{}:
+		}
+
+		sockets, err := poller.Poll(worker_poll_interval)
+		if err != nil {
+			<-handlers
+			return err
+		}
+		if len(sockets) == 0 {
+			<-handlers
+			continue
+		}
+
+		msg_raw, err := worker.RecvMessage(0)
+		if err != nil {
+			<-handlers
+			fail := message.FailWithCode(message.INTERNAL, "socket error to receive message "+err.Error())
+			if _, err := worker.SendMessage(fail.ToString()); err != nil {
+				return errors.New("failed to reply: " + err.Error())
+			}
+			continue
+		}
+
+		reply := dispatch_with_timeout(db, router, msg_raw, handler_timeout, handlers)
+
+		if _, err := worker.SendMessage(encode_reply(msg_raw, reply)); err != nil {
+			return errors.New("failed to reply: " + err.Error())
+		}
+	}
+}
+
+// Calls the router. If the handler didn't return in time, then returns the fail.
+// The handler is notified through the HandlerContext.Context.
+//
+// The slot of the handlers, taken by the worker, is released when the handler returns.
+func dispatch_with_timeout(db *sql.DB, router *Router, msg_raw []string, handler_timeout time.Duration, handlers chan struct{}) message.Reply {
+	if handler_timeout <= 0 {
+		defer func() { <-handlers }()
+		return router.Dispatch(db, msg_raw)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handler_timeout)
+
+	// buffered, so the late handler won't block forever
+	replies := make(chan message.Reply, 1)
+	go func() {
+		defer func() { <-handlers }()
+		defer cancel()
+		replies <- router.DispatchContext(ctx, db, msg_raw)
+	}()
+
+	select {
+	case reply := <-replies:
+		return reply
+	case <-ctx.Done():
+		fail := message.FailWithCode(message.TIMEOUT, "timeout: the handler didn't reply in "+handler_timeout.String())
+		if request, err := message.ParseRequest(msg_raw); err == nil {
+			fail.Header = request.Header
+		}
+		return fail
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// The table where NonceStore keeps the last accepted nonce of every address
+const NONCE_TABLE = "smartcontract_developer_nonces"
+
+// Keeps the nonces of the smartcontract developers in the database.
+// It implements account.NonceStore, so that the nonces are shared by all
+// instances of the service and survive the restart.
+type NonceStore struct {
+	db *sql.DB
+}
+
+// Creates the nonce store. Call CreateTable() once to make sure the table exists.
+func NewNonceStore(db *sql.DB) *NonceStore {
+	return &NonceStore{db: db}
+}
+
+// Creates the nonce table if it doesn't exist
+func (store *NonceStore) CreateTable() error {
+	_, err := store.db.Exec(`CREATE TABLE IF NOT EXISTS ` + NONCE_TABLE + ` (
+		address VARCHAR(42) NOT NULL PRIMARY KEY,
+		nonce_timestamp BIGINT UNSIGNED NOT NULL,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// Stores the nonce if it's greater than the last accepted nonce of the address.
+//
+// The row of the address is locked by SELECT ... FOR UPDATE until the transaction ends,
+// therefore the parallel requests with the same nonce are accepted only once.
+// Unlike the affected rows of the upsert, it doesn't depend on the clientFoundRows option of the DSN.
+func (store *NonceStore) Accept(address string, nonce uint64) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// the first nonce of the address. if another request inserted the row meanwhile,
+	// then the insert is ignored and the row is compared below.
+	_, err = tx.Exec(`INSERT IGNORE INTO `+NONCE_TABLE+` (address, nonce_timestamp) VALUES (?, 0)`, address)
+	if err != nil {
+		return false, err
+	}
+
+	var last uint64
+	err = tx.QueryRow(`SELECT nonce_timestamp FROM `+NONCE_TABLE+` WHERE address = ? FOR UPDATE`, address).Scan(&last)
+	if err != nil {
+		return false, err
+	}
+	if nonce <= last {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`UPDATE `+NONCE_TABLE+` SET nonce_timestamp = ? WHERE address = ?`, nonce, address)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
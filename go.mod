@@ -2,7 +2,10 @@ module github.com/blocklords/gosds
 
 go 1.19
 
-require github.com/ethereum/go-ethereum v1.10.25
+require (
+	github.com/ethereum/go-ethereum v1.10.25
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+)
 
 require (
 	github.com/DataDog/zstd v1.4.5 // indirect
@@ -30,6 +33,7 @@ require (
 	github.com/prometheus/client_model v0.2.1-0.20210607210712-147c58e9608a // indirect
 	github.com/prometheus/common v0.32.1 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
 	golang.org/x/exp v0.0.0-20220426173459-3bcf042a4bf5 // indirect
 	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
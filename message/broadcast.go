@@ -9,20 +9,41 @@ package message
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/blocklords/gosds/argument"
 )
 
+// The broadcasters send the heartbeat on this topic to tell the subscribers that they are alive.
+// Subscribers should subscribe to this topic along with their own topics.
+const HEARTBEAT_TOPIC = "sds_heartbeat"
+
+// How often the broadcaster sends the heartbeat.
+const HEARTBEAT_INTERVAL = 10 * time.Second
+
+// Amount of heartbeats that subscriber could miss before considering the broadcaster as gone.
+const HEARTBEAT_LIVENESS = 3
+
 // The broadcasters sends to all subscribers this message.
+//
+// The Sequence is increased by the broadcaster for every message of the topic.
+// The subscriber uses it to detect the dropped messages.
+// The Sequence is 0 if the broadcaster doesn't number the messages.
 type Broadcast struct {
-	Topic string
-	reply Reply
+	Topic    string
+	Sequence uint64
+	reply    Reply
 }
 
 // Convert to the format understood by the protocol
 func (b *Broadcast) ToJSON() map[string]interface{} {
 	return map[string]interface{}{
-		"topic": b.Topic,
-		"reply": b.reply.ToJSON(),
+		"topic":    b.Topic,
+		"sequence": b.Sequence,
+		"reply":    b.reply.ToJSON(),
 	}
 }
 
@@ -50,6 +71,24 @@ func NewBroadcast(topic string, reply Reply) Broadcast {
 	}
 }
 
+// Create a new heartbeat broadcast.
+// Its reply has the unix timestamp in seconds when the heartbeat was created.
+func NewHeartbeat() Broadcast {
+	reply := Reply{
+		Status:  "OK",
+		Message: "",
+		Params: map[string]interface{}{
+			"timestamp": uint64(time.Now().Unix()),
+		},
+	}
+	return NewBroadcast(HEARTBEAT_TOPIC, reply)
+}
+
+// Whether the broadcast is the heartbeat rather than the data
+func (b *Broadcast) IsHeartbeat() bool {
+	return b.Topic == HEARTBEAT_TOPIC
+}
+
 // Broadcast's actual data for the subscriber
 func (b *Broadcast) Reply() Reply {
 	return b.reply
@@ -58,12 +97,127 @@ func (b *Broadcast) Reply() Reply {
 // Is OK
 func (r *Broadcast) IsOK() bool { return r.reply.IsOK() }
 
-// Parse the zeromq messages into a broadcast
+// The version of the broadcast envelope
+const BROADCAST_VERSION uint64 = 1
+
+// The broadcast envelope header.
+// The content type is the codec of the payload.
+func (b *Broadcast) Header(codec Codec) map[string]interface{} {
+	return map[string]interface{}{
+		"version":      BROADCAST_VERSION,
+		"sequence":     b.Sequence,
+		"content_type": codec.ContentType(),
+	}
+}
+
+// The broadcast as the zeromq frames:
+//
+//	topic frame, header frame, payload frame
+//
+// The topic frame is the raw topic, so that subscribers could filter by it.
+// The header frame is a JSON object with the envelope version, sequence and content type.
+// The payload frame is the reply encoded according to the content type.
+//
+// The payload is encoded as JSON. Use EncodeFrames() for another codec.
+func (b *Broadcast) ToFrames() ([][]byte, error) {
+	return b.EncodeFrames(JsonCodec)
+}
+
+// The broadcast as the zeromq frames with the payload encoded by the codec.
+// See ToFrames()
+func (b *Broadcast) EncodeFrames(codec Codec) ([][]byte, error) {
+	header, err := json.Marshal(b.Header(codec))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := codec.Encode(b.reply.ToJSON())
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{[]byte(b.Topic), header, payload}, nil
+}
+
+// Parse the zeromq messages into a broadcast.
+//
+// The messages are expected to be the frames created by Broadcast.ToFrames().
+// The older format, where the topic is followed by the JSON of the whole broadcast, is
+// parsed only if the service runs with the --legacy-broadcast argument.
 func ParseBroadcast(msgs []string) (Broadcast, error) {
-	msg := ""
-	for _, v := range msgs {
-		msg += v
+	if len(msgs) == 3 {
+		return parse_broadcast_frames(msgs)
+	}
+
+	legacy, err := legacy_broadcast()
+	if err != nil {
+		return Broadcast{}, err
+	}
+	if !legacy {
+		return Broadcast{}, errors.New("invalid message, expected topic, header and payload frames. run with --" + argument.LEGACY_BROADCAST + " to accept the older format")
+	}
+
+	return parse_legacy_broadcast(msgs)
+}
+
+var (
+	legacy_broadcast_once sync.Once
+	legacy_broadcast_set  bool
+	legacy_broadcast_err  error
+)
+
+// Whether the service runs with the --legacy-broadcast argument.
+// The arguments don't change, so they are read only once.
+func legacy_broadcast() (bool, error) {
+	legacy_broadcast_once.Do(func() {
+		legacy_broadcast_set, legacy_broadcast_err = argument.Exist(argument.LEGACY_BROADCAST)
+	})
+
+	return legacy_broadcast_set, legacy_broadcast_err
+}
+
+// Parse the topic, header and payload frames
+func parse_broadcast_frames(msgs []string) (Broadcast, error) {
+	topic := msgs[0]
+
+	header, err := JsonCodec.Decode([]byte(msgs[1]))
+	if err != nil {
+		return Broadcast{}, errors.New("invalid broadcast header: " + err.Error())
+	}
+	version, err := GetUint64(header, "version")
+	if err != nil {
+		return Broadcast{}, err
+	}
+	if version != BROADCAST_VERSION {
+		return Broadcast{}, fmt.Errorf("unsupported broadcast version %d", version)
+	}
+	sequence, err := GetUint64(header, "sequence")
+	if err != nil {
+		return Broadcast{}, err
+	}
+	content_type, err := GetString(header, "content_type")
+	if err != nil {
+		return Broadcast{}, err
 	}
+	codec, err := CodecByContentType(content_type)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	raw_reply, err := codec.Decode([]byte(msgs[2]))
+	if err != nil {
+		return Broadcast{}, errors.New("invalid broadcast payload: " + err.Error())
+	}
+	reply, err := ParseJsonReply(raw_reply)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	return Broadcast{Topic: topic, Sequence: sequence, reply: reply}, nil
+}
+
+// Parse the older format where the topic frame is followed by the JSON of the broadcast
+func parse_legacy_broadcast(msgs []string) (Broadcast, error) {
+	msg := ToString(msgs)
 	i := strings.Index(msg, "{")
 
 	if i == -1 {
@@ -73,12 +227,8 @@ func ParseBroadcast(msgs []string) (Broadcast, error) {
 	topic := msg[:i]
 	broadcastRaw := msg[i:]
 
-	var dat map[string]interface{}
-
-	decoder := json.NewDecoder(strings.NewReader(broadcastRaw))
-	decoder.UseNumber()
-
-	if err := decoder.Decode(&dat); err != nil {
+	dat, err := JsonCodec.Decode([]byte(broadcastRaw))
+	if err != nil {
 		return Broadcast{}, err
 	}
 
@@ -92,5 +242,14 @@ func ParseBroadcast(msgs []string) (Broadcast, error) {
 		return Broadcast{}, err
 	}
 
-	return Broadcast{Topic: topic, reply: reply}, nil
+	// optional, the older broadcasters don't set it
+	var sequence uint64 = 0
+	if _, exists := dat["sequence"]; exists {
+		sequence, err = GetUint64(dat, "sequence")
+		if err != nil {
+			return Broadcast{}, err
+		}
+	}
+
+	return Broadcast{Topic: topic, Sequence: sequence, reply: reply}, nil
 }
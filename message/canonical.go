@@ -0,0 +1,296 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Returns the canonical JSON of the value.
+// The signer and the verifier of the message should hash the same bytes,
+// therefore both sides should use this encoding, whatever the language is.
+//
+// The rules follow JSON.stringify() of JavaScript for the same data:
+//
+//   - no whitespace between the tokens.
+//   - the object keys are sorted by their UTF-16 code units, as JavaScript compares the strings.
+//   - the strings are escaped only for '"', '\' and the control characters.
+//     The control characters are escaped as \b, \f, \n, \r, \t or \u00xx with the lowercase hex.
+//     The HTML characters like '<', '>', '&' and the non ASCII characters are not escaped.
+//   - the integers are written as decimal digits without the exponent, the "-0" is "0".
+//   - the other numbers are written as the shortest representation that round trips float64,
+//     the same way as JavaScript's Number.prototype.toString(), for example 1.5, 1e+21, 1e-7.
+//   - null, true and false as they are.
+//
+// The integers beyond 2^53 are the exception: they are written with all digits,
+// while JavaScript rounds them. Send them as the strings.
+// See docs/canonical_json.md for the test vectors.
+func CanonicalJSON(value interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := write_canonical(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func write_canonical(buffer *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		if v {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
+		}
+	case string:
+		return write_canonical_string(buffer, v)
+	case json.Number:
+		return write_canonical_number(buffer, string(v))
+	case *big.Int:
+		if v == nil {
+			buffer.WriteString("null")
+		} else {
+			buffer.WriteString(v.String())
+		}
+	case float64:
+		return write_canonical_float(buffer, v)
+	case float32:
+		return write_canonical_float(buffer, float64(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		buffer.WriteString(json_integer(v))
+	case map[string]interface{}:
+		return write_canonical_object(buffer, v)
+	case []interface{}:
+		buffer.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := write_canonical(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		return write_canonical_reflect(buffer, value)
+	}
+
+	return nil
+}
+
+func json_integer(value interface{}) string {
+	switch v := value.(type) {
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	default:
+		return strconv.FormatInt(reflect.ValueOf(v).Int(), 10)
+	}
+}
+
+// The slices, maps and structs of the other types.
+// The structs are encoded by encoding/json first, then made canonical.
+func write_canonical_reflect(buffer *bytes.Buffer, value interface{}) error {
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if reflected.IsNil() {
+			buffer.WriteString("null")
+			return nil
+		}
+		return write_canonical(buffer, reflected.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if reflected.Kind() == reflect.Slice && reflected.Type().Elem().Kind() == reflect.Uint8 {
+			break // []byte is a base64 string in JSON
+		}
+		list := make([]interface{}, reflected.Len())
+		for i := range list {
+			list[i] = reflected.Index(i).Interface()
+		}
+		return write_canonical(buffer, list)
+	case reflect.Map:
+		if reflected.Type().Key().Kind() != reflect.String {
+			break
+		}
+		object := make(map[string]interface{}, reflected.Len())
+		iter := reflected.MapRange()
+		for iter.Next() {
+			object[iter.Key().String()] = iter.Value().Interface()
+		}
+		return write_canonical_object(buffer, object)
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+	return write_canonical(buffer, decoded)
+}
+
+func write_canonical_object(buffer *bytes.Buffer, object map[string]interface{}) error {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return utf16_less(keys[i], keys[j])
+	})
+
+	buffer.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		if err := write_canonical_string(buffer, key); err != nil {
+			return err
+		}
+		buffer.WriteByte(':')
+		if err := write_canonical(buffer, object[key]); err != nil {
+			return err
+		}
+	}
+	buffer.WriteByte('}')
+
+	return nil
+}
+
+// Compares the strings by UTF-16 code units, as JavaScript does.
+// It differs from the byte order only for the characters after U+FFFF.
+func utf16_less(a string, b string) bool {
+	a_units := utf16.Encode([]rune(a))
+	b_units := utf16.Encode([]rune(b))
+	for i := 0; i < len(a_units) && i < len(b_units); i++ {
+		if a_units[i] != b_units[i] {
+			return a_units[i] < b_units[i]
+		}
+	}
+	return len(a_units) < len(b_units)
+}
+
+const lower_hex = "0123456789abcdef"
+
+func write_canonical_string(buffer *bytes.Buffer, str string) error {
+	if !utf8.ValidString(str) {
+		return errors.New("the string is not a valid UTF-8: " + strconv.Quote(str))
+	}
+
+	buffer.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch c {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\b':
+			buffer.WriteString(`\b`)
+		case '\f':
+			buffer.WriteString(`\f`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buffer.WriteString(`\u00`)
+				buffer.WriteByte(lower_hex[c>>4])
+				buffer.WriteByte(lower_hex[c&0xf])
+			} else {
+				buffer.WriteByte(c)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+
+	return nil
+}
+
+// The decoded number is kept as the digits if it's an integer,
+// otherwise it's formatted as float64.
+func write_canonical_number(buffer *bytes.Buffer, number string) error {
+	integer, ok := new(big.Int).SetString(number, 10)
+	if ok {
+		buffer.WriteString(integer.String())
+		return nil
+	}
+
+	float, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return errors.New("invalid number '" + number + "'")
+	}
+	return write_canonical_float(buffer, float)
+}
+
+// Formats the number as ECMAScript Number::toString does.
+func write_canonical_float(buffer *bytes.Buffer, float float64) error {
+	if math.IsNaN(float) || math.IsInf(float, 0) {
+		return errors.New("NaN and infinite numbers are not supported by JSON")
+	}
+	if float == 0 {
+		buffer.WriteByte('0')
+		return nil
+	}
+	if float < 0 {
+		buffer.WriteByte('-')
+		float = -float
+	}
+
+	// the shortest digits that round trip, and the exponent n where value = 0.digits * 10^n
+	formatted := strconv.FormatFloat(float, 'e', -1, 64)
+	mantissa, exponent_str, _ := strings.Cut(formatted, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exponent, _ := strconv.Atoi(exponent_str)
+	k := len(digits)
+	n := exponent + 1
+
+	switch {
+	case k <= n && n <= 21:
+		buffer.WriteString(digits)
+		buffer.WriteString(strings.Repeat("0", n-k))
+	case 0 < n && n <= 21:
+		buffer.WriteString(digits[:n])
+		buffer.WriteByte('.')
+		buffer.WriteString(digits[n:])
+	case -6 < n && n <= 0:
+		buffer.WriteString("0.")
+		buffer.WriteString(strings.Repeat("0", -n))
+		buffer.WriteString(digits)
+	default:
+		buffer.WriteByte(digits[0])
+		if k > 1 {
+			buffer.WriteByte('.')
+			buffer.WriteString(digits[1:])
+		}
+		buffer.WriteByte('e')
+		if n-1 >= 0 {
+			buffer.WriteByte('+')
+		}
+		buffer.WriteString(strconv.Itoa(n - 1))
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package message
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The test vectors of docs/canonical_json.md and docs/eip712.md
+
+const vector_private_key = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func vector_request() SmartcontractDeveloperRequest {
+	return SmartcontractDeveloperRequest{
+		Address:        "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+		NonceTimestamp: 1672531200,
+		Command:        "smartcontract_write",
+		Parameters: map[string]interface{}{
+			"topic_string": "o:seascape;p:blocklords;n:1;g:nft;s:Hero;m:mint",
+			"arguments": map[string]interface{}{
+				"to":     "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+				"amount": "1000000000000000000",
+			},
+			"address": "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+		},
+	}
+}
+
+func TestCanonicalJSONEncoding(t *testing.T) {
+	vectors := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b":1,"a":2}`, `{"a":2,"b":1}`},
+		{`{"nested":{"z":true,"a":null},"list":[3,"x",{"b":1,"a":2}]}`, `{"list":[3,"x",{"a":2,"b":1}],"nested":{"a":null,"z":true}}`},
+		{`{"html":"<a href=\"x\">&</a>","unicode":"héllo ✓","control":"tab\tnew\nline\u0001"}`, `{"control":"tab\tnew\nline\u0001","html":"<a href=\"x\">&</a>","unicode":"héllo ✓"}`},
+		{`[0,-0,1.0,1.5,-2.25,0.000001,1e-7,1e21,100e0,1E2]`, `[0,0,1,1.5,-2.25,0.000001,1e-7,1e+21,100,100]`},
+		{`{"é":1,"z":2,"😀":3,"｡":4}`, `{"z":2,"é":1,"😀":3,"｡":4}`},
+	}
+
+	for _, vector := range vectors {
+		decoder := json.NewDecoder(strings.NewReader(vector.input))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatalf("failed to decode %s: %v", vector.input, err)
+		}
+
+		canonical, err := CanonicalJSON(value)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", vector.input, err)
+		}
+		if string(canonical) != vector.expected {
+			t.Errorf("canonical json of %s\n got: %s\nwant: %s", vector.input, canonical, vector.expected)
+		}
+	}
+}
+
+func TestSignedRequestVector(t *testing.T) {
+	request := vector_request()
+
+	json_object := request.ToJSON()
+	delete(json_object, "signature")
+	delete(json_object, "signature_scheme")
+	delete(json_object, "header")
+	canonical, err := CanonicalJSON(json_object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected_json := `{"address":"0x2c7536E3605D9C16a7a3D7b1898e529396a65c23","command":"smartcontract_write","nonce_timestamp":1672531200,"parameters":{"address":"0x2c7536E3605D9C16a7a3D7b1898e529396a65c23","arguments":{"amount":"1000000000000000000","to":"0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},"topic_string":"o:seascape;p:blocklords;n:1;g:nft;s:Hero;m:mint"}}`
+	if string(canonical) != expected_json {
+		t.Errorf("canonical json\n got: %s\nwant: %s", canonical, expected_json)
+	}
+
+	if hash := hexutil.Encode(request.message_hash()); hash != "0x4ba6e0d4e2a849871b5b5e2b893815fe6de04f6f93b9bfa46b49068ad0a60e9a" {
+		t.Errorf("message hash %s", hash)
+	}
+	if digest := hexutil.Encode(request.DigestedMessage()); digest != "0x5515edee1b8beb498d36669a97e121aaeebe5d8899d5abeecbb28f7b63dcd532" {
+		t.Errorf("digest %s", digest)
+	}
+
+	private_key, err := crypto.HexToECDSA(vector_private_key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.Sign(private_key); err != nil {
+		t.Fatal(err)
+	}
+	if request.Signature != "0xfcd8e1b03128610160a1855c3ce7a001d8157c7adb6f74425b4895b9e52ab07322c51d39b7522895d3ebc96f0bc89b9a5e947ca46296ef8e7c11c16468cc95b41b" {
+		t.Errorf("signature %s", request.Signature)
+	}
+}
+
+func TestEip712Vector(t *testing.T) {
+	domain := Eip712Domain{Name: EIP712_NAME, Version: EIP712_VERSION, ChainId: EIP712_CHAIN_ID}
+	if separator := hexutil.Encode(domain.Separator()); separator != "0xa6c16a407e76ed7c3cdb80a886034a394c47064919153943b8fedbc2d076e348" {
+		t.Errorf("domain separator %s", separator)
+	}
+
+	request := vector_request()
+	request.SignatureScheme = EIP712_SCHEME
+	if digest := hexutil.Encode(request.eip712_digest(domain)); digest != "0x77a6ffe77a472878c7773fe807beecb11246e935e296b7c7a5480dc14f15dfbe" {
+		t.Errorf("digest %s", digest)
+	}
+
+	private_key, err := crypto.HexToECDSA(vector_private_key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetEip712Domain(domain)
+	if err := request.Sign(private_key); err != nil {
+		t.Fatal(err)
+	}
+	if request.Signature != "0x0d8ea420c3f3fa7403765dd6541ddab570b0782ea877d628294c7f3b7b8262cd3daefa40056cb360c51e020ed6661153a83f4d1d012d7841f60ae58bb3d56a961b" {
+		t.Errorf("signature %s", request.Signature)
+	}
+}
@@ -0,0 +1,169 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The content type of the JSON encoded messages
+const JSON_CONTENT_TYPE = "application/json"
+
+// The content type of the MessagePack encoded messages
+const MSGPACK_CONTENT_TYPE = "application/msgpack"
+
+// The Codec converts the message objects into the bytes and back.
+//
+// The JSON messages are sent as a single zeromq frame, as they always were.
+// Messages in other codecs are sent as two frames: the content type and the encoded message.
+// The reply is encoded by the same codec as the request.
+type Codec interface {
+	ContentType() string
+	Encode(object map[string]interface{}) ([]byte, error)
+	Decode(data []byte) (map[string]interface{}, error)
+}
+
+var (
+	JsonCodec    Codec = json_codec{}
+	MsgpackCodec Codec = msgpack_codec{}
+)
+
+// Returns the codec by its content type
+func CodecByContentType(content_type string) (Codec, error) {
+	switch content_type {
+	case JSON_CONTENT_TYPE:
+		return JsonCodec, nil
+	case MSGPACK_CONTENT_TYPE:
+		return MsgpackCodec, nil
+	default:
+		return nil, errors.New("unsupported content type " + content_type)
+	}
+}
+
+// Returns the codec that the zeromq frames were encoded with
+func FramesCodec(msgs []string) Codec {
+	if len(msgs) == 2 {
+		codec, err := CodecByContentType(msgs[0])
+		if err == nil {
+			return codec
+		}
+	}
+
+	return JsonCodec
+}
+
+// Decodes the zeromq frames into the message object
+func DecodeFrames(msgs []string) (map[string]interface{}, error) {
+	if len(msgs) == 2 {
+		if codec, err := CodecByContentType(msgs[0]); err == nil {
+			return codec.Decode([]byte(msgs[1]))
+		}
+	}
+
+	return JsonCodec.Decode([]byte(ToString(msgs)))
+}
+
+// Encodes the message object into the zeromq frames
+func EncodeFrames(codec Codec, object map[string]interface{}) ([]string, error) {
+	data, err := codec.Encode(object)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec.ContentType() == JSON_CONTENT_TYPE {
+		return []string{string(data)}, nil
+	}
+
+	return []string{codec.ContentType(), string(data)}, nil
+}
+
+type json_codec struct{}
+
+func (json_codec) ContentType() string { return JSON_CONTENT_TYPE }
+
+func (json_codec) Encode(object map[string]interface{}) ([]byte, error) {
+	return json.Marshal(object)
+}
+
+// The numbers are decoded as json.Number to keep the precision
+func (json_codec) Decode(data []byte) (map[string]interface{}, error) {
+	var dat map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+type msgpack_codec struct{}
+
+func (msgpack_codec) ContentType() string { return MSGPACK_CONTENT_TYPE }
+
+func (msgpack_codec) Encode(object map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+
+	if err := encoder.Encode(from_json_numbers(object)); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// The integers are decoded as int64 or uint64, the other numbers as float64
+func (msgpack_codec) Decode(data []byte) (map[string]interface{}, error) {
+	var dat map[string]interface{}
+
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.UseLooseInterfaceDecoding(true)
+
+	if err := decoder.Decode(&dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// The messages decoded from JSON keep the numbers as json.Number, which is a string.
+// Converts them into the numbers, so that other codecs don't encode them as strings.
+func from_json_numbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		str := string(v)
+		if !strings.ContainsAny(str, ".eE") {
+			if number, err := strconv.ParseInt(str, 10, 64); err == nil {
+				return number
+			}
+			if number, err := strconv.ParseUint(str, 10, 64); err == nil {
+				return number
+			}
+		}
+		if number, err := v.Float64(); err == nil {
+			return number
+		}
+		return str
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			converted[key] = from_json_numbers(element)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, element := range v {
+			converted[i] = from_json_numbers(element)
+		}
+		return converted
+	default:
+		return value
+	}
+}
@@ -1,9 +1,7 @@
 package message
 
 import (
-	"encoding/json"
 	"errors"
-	"strconv"
 )
 
 // Returns the parameter as an uint64
@@ -13,32 +11,27 @@ func GetUint64(parameters map[string]interface{}, name string) (uint64, error) {
 		return 0, errors.New("missing '" + name + "' parameter in the Request")
 	}
 
-	pure_value, ok := raw.(uint64)
-	if ok {
-		return pure_value, nil
+	value, err := uint64_value(raw)
+	if err != nil {
+		return 0, errors.New("parameter '" + name + "' " + err.Error())
 	}
-	value, ok := raw.(json.Number)
-	if !ok {
-		return 0, errors.New("parameter '" + name + "' expected to be as a number")
-	}
-
-	number, err := strconv.ParseUint(string(value), 10, 64)
 
-	return number, err
+	return value, nil
 }
 
+// Returns the parameter as a float64
 func GetFloat64(parameters map[string]interface{}, name string) (float64, error) {
 	raw, exists := parameters[name]
 	if !exists {
 		return 0, errors.New("missing '" + name + "' parameter in the Request")
 	}
-	pure_value, ok := raw.(float64)
-	if ok {
-		return pure_value, nil
+
+	value, err := float64_value(raw)
+	if err != nil {
+		return 0, errors.New("parameter '" + name + "' " + err.Error())
 	}
-	value, err := raw.(json.Number).Float64()
 
-	return value, err
+	return value, nil
 }
 
 // Returns the paramater as a string
@@ -0,0 +1,164 @@
+package message
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/blocklords/gosds/env"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The EIP-712 domain of the SDS deployment.
+// The signature made for one deployment is not valid for another one.
+//
+// See https://eips.ethereum.org/EIPS/eip-712
+type Eip712Domain struct {
+	Name    string
+	Version string
+	ChainId uint64
+}
+
+// The default domain, if the environment variables are not set
+const (
+	EIP712_NAME     = "SeascapeSDS"
+	EIP712_VERSION  = "1"
+	EIP712_CHAIN_ID = 1
+)
+
+// The EIP-712 type of the request.
+// The parameters are the canonical JSON of the request parameters, see CanonicalJSON().
+const EIP712_REQUEST_TYPE = "SmartcontractDeveloperRequest(address account,uint64 nonceTimestamp,string command,string parameters)"
+
+const eip712_domain_type = "EIP712Domain(string name,string version,uint256 chainId)"
+
+var (
+	eip712_domain_mu  sync.RWMutex
+	eip712_domain     Eip712Domain
+	eip712_domain_set bool
+)
+
+// Returns the EIP-712 domain of the SDS deployment.
+//
+// Unless set by SetEip712Domain(), it's read from the 'SDS_EIP712_NAME',
+// 'SDS_EIP712_VERSION' and 'SDS_EIP712_CHAIN_ID' environment variables.
+// The missing variables are EIP712_NAME, EIP712_VERSION and EIP712_CHAIN_ID.
+func GetEip712Domain() Eip712Domain {
+	eip712_domain_mu.RLock()
+	if eip712_domain_set {
+		defer eip712_domain_mu.RUnlock()
+		return eip712_domain
+	}
+	eip712_domain_mu.RUnlock()
+
+	domain := Eip712Domain{
+		Name:    EIP712_NAME,
+		Version: EIP712_VERSION,
+		ChainId: EIP712_CHAIN_ID,
+	}
+	if env.Exists("SDS_EIP712_NAME") {
+		domain.Name = env.GetString("SDS_EIP712_NAME")
+	}
+	if env.Exists("SDS_EIP712_VERSION") {
+		domain.Version = env.GetString("SDS_EIP712_VERSION")
+	}
+	if env.Exists("SDS_EIP712_CHAIN_ID") {
+		domain.ChainId = uint64(env.GetNumeric("SDS_EIP712_CHAIN_ID"))
+	}
+
+	return domain
+}
+
+// Set the EIP-712 domain used by the requests signed with EIP712_SCHEME.
+func SetEip712Domain(domain Eip712Domain) {
+	eip712_domain_mu.Lock()
+	eip712_domain = domain
+	eip712_domain_set = true
+	eip712_domain_mu.Unlock()
+}
+
+// The domain separator, hashStruct(domain)
+func (domain *Eip712Domain) Separator() []byte {
+	return crypto.Keccak256(
+		crypto.Keccak256([]byte(eip712_domain_type)),
+		crypto.Keccak256([]byte(domain.Name)),
+		crypto.Keccak256([]byte(domain.Version)),
+		math.U256Bytes(new(big.Int).SetUint64(domain.ChainId)),
+	)
+}
+
+// The domain as the typed data JSON
+func (domain *Eip712Domain) ToJSON() map[string]interface{} {
+	return map[string]interface{}{
+		"name":    domain.Name,
+		"version": domain.Version,
+		"chainId": domain.ChainId,
+	}
+}
+
+// The hashStruct of the request according to EIP712_REQUEST_TYPE.
+func (request *SmartcontractDeveloperRequest) eip712_hash_struct() ([]byte, error) {
+	if !common.IsHexAddress(request.Address) {
+		return nil, errors.New("the request 'address' is not a valid address")
+	}
+	parameters, err := CanonicalJSON(request.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.Keccak256(
+		crypto.Keccak256([]byte(EIP712_REQUEST_TYPE)),
+		common.LeftPadBytes(common.HexToAddress(request.Address).Bytes(), 32),
+		math.U256Bytes(new(big.Int).SetUint64(request.NonceTimestamp)),
+		crypto.Keccak256([]byte(request.Command)),
+		crypto.Keccak256(parameters),
+	), nil
+}
+
+// The EIP-712 digest of the request in the domain:
+//
+//	keccak256("\x19\x01" || domainSeparator || hashStruct(request))
+func (request *SmartcontractDeveloperRequest) eip712_digest(domain Eip712Domain) []byte {
+	hash_struct, err := request.eip712_hash_struct()
+	if err != nil {
+		return []byte{}
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domain.Separator(), hash_struct)
+}
+
+// The typed data of the request for the wallets, the eth_signTypedData_v4 argument.
+// The wallets show the command and the parameters to the user before signing.
+func (request *SmartcontractDeveloperRequest) Eip712TypedData() (map[string]interface{}, error) {
+	parameters, err := CanonicalJSON(request.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	domain := GetEip712Domain()
+
+	return map[string]interface{}{
+		"types": map[string]interface{}{
+			"EIP712Domain": []interface{}{
+				map[string]interface{}{"name": "name", "type": "string"},
+				map[string]interface{}{"name": "version", "type": "string"},
+				map[string]interface{}{"name": "chainId", "type": "uint256"},
+			},
+			"SmartcontractDeveloperRequest": []interface{}{
+				map[string]interface{}{"name": "account", "type": "address"},
+				map[string]interface{}{"name": "nonceTimestamp", "type": "uint64"},
+				map[string]interface{}{"name": "command", "type": "string"},
+				map[string]interface{}{"name": "parameters", "type": "string"},
+			},
+		},
+		"primaryType": "SmartcontractDeveloperRequest",
+		"domain":      domain.ToJSON(),
+		"message": map[string]interface{}{
+			"account":        request.Address,
+			"nonceTimestamp": strconv.FormatUint(request.NonceTimestamp, 10),
+			"command":        request.Command,
+			"parameters":     string(parameters),
+		},
+	}, nil
+}
@@ -0,0 +1,49 @@
+package message
+
+import "errors"
+
+// The machine readable reason of the failure reply.
+// The free text Message of the reply is for humans, and could change any time.
+type ErrorCode string
+
+const (
+	INTERNAL            ErrorCode = "INTERNAL"            // the service failed by itself
+	NOT_FOUND           ErrorCode = "NOT_FOUND"           // the requested data doesn't exist
+	UNSUPPORTED_COMMAND ErrorCode = "UNSUPPORTED_COMMAND" // the service has no such command
+	UNAUTHORIZED        ErrorCode = "UNAUTHORIZED"        // the requester is not allowed to call the command
+	INVALID_NONCE       ErrorCode = "INVALID_NONCE"       // the nonce is out of the acceptance window or was already used
+	INVALID_PARAMS      ErrorCode = "INVALID_PARAMS"      // the request or its parameters are invalid
+	TIMEOUT             ErrorCode = "TIMEOUT"             // the reply or the broadcast didn't arrive in time
+	RATE_LIMITED        ErrorCode = "RATE_LIMITED"        // too many requests
+	CHAIN_REVERTED      ErrorCode = "CHAIN_REVERTED"      // the blockchain reverted the transaction
+	GAP                 ErrorCode = "GAP"                 // the subscriber missed some broadcasts
+)
+
+// Create a new Reply as a failure with the error code.
+// The details are set by the Reply.Details.
+func FailWithCode(code ErrorCode, message string) Reply {
+	reply := Fail(message)
+	reply.Code = code
+	return reply
+}
+
+// Create a new failure reply about the invalid parameters.
+// If the error was returned by DecodeParams(), then the invalid fields are
+// listed in the "fields" detail as the list of {"path", "message"}.
+func InvalidParams(err error) Reply {
+	reply := FailWithCode(INVALID_PARAMS, err.Error())
+
+	var params_error *ParamsError
+	if errors.As(err, &params_error) {
+		fields := make([]interface{}, len(params_error.Fields))
+		for i, field := range params_error.Fields {
+			fields[i] = map[string]interface{}{
+				"path":    field.Path,
+				"message": field.Message,
+			}
+		}
+		reply.Details = map[string]interface{}{"fields": fields}
+	}
+
+	return reply
+}
@@ -0,0 +1,170 @@
+package message
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// The optional envelope of the request and reply for correlation and tracing.
+//
+// The requester sets it, and the SDS Service echoes it back in the reply.
+// The header is not part of the signed message of SmartcontractDeveloperRequest.
+//
+// The times are sent as unix timestamps in milliseconds.
+type Header struct {
+	RequestId   string    // unique id of the request, to find it in the logs
+	CreatedAt   time.Time // when the request was created
+	Deadline    time.Time // the requester doesn't wait for the reply after it. Zero if not set.
+	Traceparent string    // W3C trace context, https://www.w3.org/TR/trace-context/#traceparent-header
+}
+
+// The header with the random request id, created now.
+func NewHeader() Header {
+	return Header{
+		RequestId: NewRequestId(),
+		CreatedAt: time.Now(),
+	}
+}
+
+// Returns a random 16 bytes id as a hex string
+func NewRequestId() string {
+	return random_hex(16)
+}
+
+// Whether the message has no header
+func (header *Header) IsEmpty() bool {
+	return header.RequestId == "" && header.CreatedAt.IsZero() && header.Deadline.IsZero() && header.Traceparent == ""
+}
+
+// Whether the requester set the deadline
+func (header *Header) HasDeadline() bool {
+	return !header.Deadline.IsZero()
+}
+
+// Convert to JSON.
+// The fields that are not set are omitted.
+func (header *Header) ToJSON() map[string]interface{} {
+	i := map[string]interface{}{}
+	if header.RequestId != "" {
+		i["request_id"] = header.RequestId
+	}
+	if !header.CreatedAt.IsZero() {
+		i["created_at"] = uint64(header.CreatedAt.UnixMilli())
+	}
+	if header.HasDeadline() {
+		i["deadline"] = uint64(header.Deadline.UnixMilli())
+	}
+	if header.Traceparent != "" {
+		i["traceparent"] = header.Traceparent
+	}
+	return i
+}
+
+// Parse the optional "header" of the message.
+// If the message has no header, then returns the empty header.
+//
+// The invalid traceparent is ignored, as the W3C trace context requires.
+func ParseHeader(dat map[string]interface{}) (Header, error) {
+	header := Header{}
+
+	raw, exists := dat["header"]
+	if !exists || raw == nil {
+		return header, nil
+	}
+	parameters, ok := raw.(map[string]interface{})
+	if !ok {
+		return header, errors.New("expected map type for 'header' parameter")
+	}
+
+	if _, exists := parameters["request_id"]; exists {
+		request_id, err := GetString(parameters, "request_id")
+		if err != nil {
+			return header, err
+		}
+		header.RequestId = request_id
+	}
+	if _, exists := parameters["created_at"]; exists {
+		created_at, err := GetUint64(parameters, "created_at")
+		if err != nil {
+			return header, err
+		}
+		header.CreatedAt = time.UnixMilli(int64(created_at))
+	}
+	if _, exists := parameters["deadline"]; exists {
+		deadline, err := GetUint64(parameters, "deadline")
+		if err != nil {
+			return header, err
+		}
+		header.Deadline = time.UnixMilli(int64(deadline))
+	}
+	if _, exists := parameters["traceparent"]; exists {
+		traceparent, err := GetString(parameters, "traceparent")
+		if err != nil {
+			return header, err
+		}
+		if ValidTraceparent(traceparent) {
+			header.Traceparent = traceparent
+		}
+	}
+
+	return header, nil
+}
+
+var traceparent_format = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// Whether the traceparent is in the W3C format:
+//
+//	version-trace_id-parent_id-flags
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ValidTraceparent(traceparent string) bool {
+	parts := traceparent_format.FindStringSubmatch(traceparent)
+	if parts == nil {
+		return false
+	}
+	// the version "ff" and the ids with all zeros are invalid
+	return parts[1] != "ff" &&
+		parts[2] != "00000000000000000000000000000000" &&
+		parts[3] != "0000000000000000"
+}
+
+// Starts a new sampled trace
+func NewTraceparent() string {
+	return "00-" + random_hex(16) + "-" + random_hex(8) + "-01"
+}
+
+// The traceparent for the outgoing request within the same trace.
+// The trace id and flags are kept, the parent id is new.
+// If the traceparent is not valid, then a new trace is started.
+func ChildTraceparent(traceparent string) string {
+	if !ValidTraceparent(traceparent) {
+		return NewTraceparent()
+	}
+	return traceparent[:36] + random_hex(8) + traceparent[52:]
+}
+
+func random_hex(size int) string {
+	bytes := make([]byte, size)
+	// the crypto/rand doesn't fail on the supported platforms
+	if _, err := rand.Read(bytes); err != nil {
+		panic("failed to generate random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+type header_key struct{}
+
+// Returns the context that carries the header of the request.
+// The requests sent by the remote package with this context continue its trace.
+func ContextWithHeader(ctx context.Context, header Header) context.Context {
+	return context.WithValue(ctx, header_key{}, header)
+}
+
+// Returns the header of the request that the context carries.
+func HeaderFromContext(ctx context.Context) (Header, bool) {
+	header, ok := ctx.Value(header_key{}).(Header)
+	return header, ok
+}
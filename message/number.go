@@ -0,0 +1,230 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	big_int_type = reflect.TypeOf(big.Int{})
+	big_rat_type = reflect.TypeOf(big.Rat{})
+)
+
+// Returns the parameter as a big integer.
+// Use it for the token amounts, that don't fit into uint64.
+//
+// The parameter could be a number, the decimal string like "1000000000000000000"
+// or the hex string like "0xde0b6b3a7640000".
+func GetBigInt(parameters map[string]interface{}, name string) (*big.Int, error) {
+	raw, exists := parameters[name]
+	if !exists {
+		return nil, errors.New("missing '" + name + "' parameter in the Request")
+	}
+
+	value, err := big_int_value(raw)
+	if err != nil {
+		return nil, errors.New("parameter '" + name + "' " + err.Error())
+	}
+
+	return value, nil
+}
+
+// Returns the parameter as an exact decimal number.
+//
+// The parameter could be a number, the decimal string like "0.000000000000000001",
+// or the hex string of an integer.
+// Unlike GetFloat64() the digits are not lost.
+func GetDecimal(parameters map[string]interface{}, name string) (*big.Rat, error) {
+	raw, exists := parameters[name]
+	if !exists {
+		return nil, errors.New("missing '" + name + "' parameter in the Request")
+	}
+
+	value, err := big_rat_value(raw)
+	if err != nil {
+		return nil, errors.New("parameter '" + name + "' " + err.Error())
+	}
+
+	return value, nil
+}
+
+// Returns the decimal as a string without the exponent and without losing the digits.
+// If the decimal has infinite digits, like 1/3, then it's returned as a fraction.
+func DecimalString(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String()
+	}
+
+	// the decimal is finite if the denominator has only 2 and 5 as divisors
+	denominator := new(big.Int).Set(value.Denom())
+	two := big.NewInt(2)
+	five := big.NewInt(5)
+	twos, fives := 0, 0
+	modulus := new(big.Int)
+	for modulus.Mod(denominator, two).Sign() == 0 {
+		denominator.Quo(denominator, two)
+		twos++
+	}
+	for modulus.Mod(denominator, five).Sign() == 0 {
+		denominator.Quo(denominator, five)
+		fives++
+	}
+	if denominator.Cmp(big.NewInt(1)) != 0 {
+		return value.RatString()
+	}
+
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return value.FloatString(digits)
+}
+
+func big_int_value(raw interface{}) (*big.Int, error) {
+	switch value := raw.(type) {
+	case *big.Int:
+		if value == nil {
+			return nil, errors.New("expected to be a number")
+		}
+		return new(big.Int).Set(value), nil
+	case big.Int:
+		return new(big.Int).Set(&value), nil
+	case string:
+		return parse_big_int(value)
+	case json.Number:
+		return parse_big_int(string(value))
+	case float64:
+		if value != math.Trunc(value) || math.IsInf(value, 0) {
+			return nil, errors.New("expected to be an integer")
+		}
+		number, _ := big.NewFloat(value).Int(nil)
+		return number, nil
+	case float32:
+		return big_int_value(float64(value))
+	default:
+		number, err := int64_value(raw)
+		if err == nil {
+			return big.NewInt(number), nil
+		}
+		unsigned, err := uint64_value(raw)
+		if err != nil {
+			return nil, errors.New("expected to be a number")
+		}
+		return new(big.Int).SetUint64(unsigned), nil
+	}
+}
+
+// Parses the decimal or hex string.
+// The "1e18" notation is accepted as long as the result is an integer, see MAX_EXPONENT.
+func parse_big_int(str string) (*big.Int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil, errors.New("expected to be a number, got an empty string")
+	}
+
+	if has_hex_prefix(str) {
+		number, ok := new(big.Int).SetString(str, 0)
+		if !ok {
+			return nil, errors.New("invalid hex number '" + str + "'")
+		}
+		return number, nil
+	}
+
+	number, ok := new(big.Int).SetString(str, 10)
+	if ok {
+		return number, nil
+	}
+
+	if err := check_exponent(str); err != nil {
+		return nil, err
+	}
+	decimal, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, errors.New("invalid number '" + str + "'")
+	}
+	if !decimal.IsInt() {
+		return nil, errors.New("expected to be an integer, got '" + str + "'")
+	}
+	return new(big.Int).Set(decimal.Num()), nil
+}
+
+func big_rat_value(raw interface{}) (*big.Rat, error) {
+	switch value := raw.(type) {
+	case *big.Rat:
+		if value == nil {
+			return nil, errors.New("expected to be a number")
+		}
+		return new(big.Rat).Set(value), nil
+	case big.Rat:
+		return new(big.Rat).Set(&value), nil
+	case string:
+		return parse_big_rat(value)
+	case json.Number:
+		return parse_big_rat(string(value))
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, errors.New("expected to be a number")
+		}
+		return new(big.Rat).SetFloat64(value), nil
+	case float32:
+		return big_rat_value(float64(value))
+	default:
+		number, err := big_int_value(raw)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).SetInt(number), nil
+	}
+}
+
+func parse_big_rat(str string) (*big.Rat, error) {
+	str = strings.TrimSpace(str)
+	if has_hex_prefix(str) {
+		number, err := parse_big_int(str)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).SetInt(number), nil
+	}
+
+	if err := check_exponent(str); err != nil {
+		return nil, err
+	}
+	decimal, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, errors.New("invalid decimal number '" + str + "'")
+	}
+	return decimal, nil
+}
+
+// The largest exponent of the "1e18" notation.
+// The uint256 has 78 digits, the larger exponents would only make the huge numbers.
+const MAX_EXPONENT = 78
+
+// Rejects the "1e18" notation with the exponent beyond MAX_EXPONENT,
+// before big.Rat computes the power of ten.
+func check_exponent(str string) error {
+	i := strings.IndexAny(str, "eE")
+	if i == -1 {
+		return nil
+	}
+
+	exponent, err := strconv.ParseInt(str[i+1:], 10, 64)
+	if err != nil {
+		return errors.New("invalid exponent of the number '" + str + "'")
+	}
+	if exponent > MAX_EXPONENT || exponent < -MAX_EXPONENT {
+		return errors.New("the exponent of the number '" + str + "' exceeds " + strconv.Itoa(MAX_EXPONENT))
+	}
+	return nil
+}
+
+func has_hex_prefix(str string) bool {
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "-"), "+")
+	return strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X")
+}
@@ -0,0 +1,191 @@
+package message
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestGetBigInt(t *testing.T) {
+	vectors := []struct {
+		raw      interface{}
+		expected string
+	}{
+		{"1000000000000000000", "1000000000000000000"},
+		{" 42 ", "42"},
+		{"-1", "-1"},
+		{"0xde0b6b3a7640000", "1000000000000000000"},
+		{"0XFF", "255"},
+		{"-0x10", "-16"},
+		{"1e18", "1000000000000000000"},
+		{"1E18", "1000000000000000000"},
+		{"-2.5e1", "-25"},
+		{"1.5e1", "15"},
+		{"1e78", "1" + strings.Repeat("0", 78)},
+		{"1e+78", "1" + strings.Repeat("0", 78)},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{json.Number("123456789012345678901234567890"), "123456789012345678901234567890"},
+		{float64(1e18), "1000000000000000000"},
+		{float64(-3), "-3"},
+		{float32(7), "7"},
+		{int(-5), "-5"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{big.NewInt(9), "9"},
+		{*big.NewInt(10), "10"},
+	}
+
+	for _, vector := range vectors {
+		number, err := GetBigInt(map[string]interface{}{"amount": vector.raw}, "amount")
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", vector.raw, err)
+			continue
+		}
+		if number.String() != vector.expected {
+			t.Errorf("%v: got %s, want %s", vector.raw, number.String(), vector.expected)
+		}
+	}
+}
+
+func TestGetBigIntErrors(t *testing.T) {
+	vectors := []interface{}{
+		"",
+		"   ",
+		"abc",
+		"1.5",
+		"1e-1",
+		"1e79",
+		"1e-79",
+		"1e",
+		"1e1.5",
+		"1e99999999999999999999",
+		"0x",
+		"0xzz",
+		float64(1.5),
+		(*big.Int)(nil),
+		true,
+		[]interface{}{"1"},
+	}
+
+	for _, raw := range vectors {
+		if number, err := GetBigInt(map[string]interface{}{"amount": raw}, "amount"); err == nil {
+			t.Errorf("%v: expected an error, got %s", raw, number.String())
+		}
+	}
+
+	if _, err := GetBigInt(map[string]interface{}{}, "amount"); err == nil {
+		t.Error("expected an error for the missing parameter")
+	}
+}
+
+func TestGetDecimal(t *testing.T) {
+	vectors := []struct {
+		raw      interface{}
+		expected string
+	}{
+		{"0.000000000000000001", "0.000000000000000001"},
+		{"-0.5", "-0.5"},
+		{"1/3", "1/3"},
+		{"12", "12"},
+		{"0x10", "16"},
+		{"-0x10", "-16"},
+		{"1e-18", "0.000000000000000001"},
+		{"2.5E3", "2500"},
+		{"1e-78", "0." + strings.Repeat("0", 77) + "1"},
+		{"-1e78", "-1" + strings.Repeat("0", 78)},
+		{json.Number("0.1"), "0.1"},
+		{float64(0.5), "0.5"},
+		{int64(-7), "-7"},
+		{big.NewRat(1, 4), "0.25"},
+	}
+
+	for _, vector := range vectors {
+		number, err := GetDecimal(map[string]interface{}{"price": vector.raw}, "price")
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", vector.raw, err)
+			continue
+		}
+		if DecimalString(number) != vector.expected {
+			t.Errorf("%v: got %s, want %s", vector.raw, DecimalString(number), vector.expected)
+		}
+	}
+}
+
+func TestGetDecimalErrors(t *testing.T) {
+	vectors := []interface{}{
+		"",
+		"abc",
+		"1.2.3",
+		"1e79",
+		"1e-79",
+		"1e",
+		"0x1.5",
+		"0xzz",
+		(*big.Rat)(nil),
+		true,
+	}
+
+	for _, raw := range vectors {
+		if number, err := GetDecimal(map[string]interface{}{"price": raw}, "price"); err == nil {
+			t.Errorf("%v: expected an error, got %s", raw, number.String())
+		}
+	}
+
+	if _, err := GetDecimal(map[string]interface{}{}, "price"); err == nil {
+		t.Error("expected an error for the missing parameter")
+	}
+}
+
+func TestCheckExponent(t *testing.T) {
+	vectors := []struct {
+		str   string
+		valid bool
+	}{
+		{"1", true},
+		{"1.5", true},
+		{"1e0", true},
+		{"1e78", true},
+		{"1E78", true},
+		{"1e+78", true},
+		{"1e-78", true},
+		{"-1e78", true},
+		{"1e79", false},
+		{"1e-79", false},
+		{"1e+79", false},
+		{"1e1000000", false},
+		{"1e", false},
+		{"1e+", false},
+		{"1ex", false},
+		{"1e99999999999999999999", false},
+	}
+
+	for _, vector := range vectors {
+		err := check_exponent(vector.str)
+		if vector.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", vector.str, err)
+		}
+		if !vector.valid && err == nil {
+			t.Errorf("%s: expected an error", vector.str)
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	vectors := []struct {
+		number   *big.Rat
+		expected string
+	}{
+		{big.NewRat(0, 1), "0"},
+		{big.NewRat(-10, 1), "-10"},
+		{big.NewRat(1, 8), "0.125"},
+		{big.NewRat(-1, 20), "-0.05"},
+		{big.NewRat(2, 3), "2/3"},
+		{big.NewRat(1, 6), "1/6"},
+	}
+
+	for _, vector := range vectors {
+		if str := DecimalString(vector.number); str != vector.expected {
+			t.Errorf("%s: got %s, want %s", vector.number.String(), str, vector.expected)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The parameter that failed to be decoded.
+//
+// The Path is the location of the parameter, for example:
+//
+//	transactions[2].block_number
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return "'" + e.Path + "' " + e.Message
+}
+
+// All parameters that DecodeParams() failed to decode.
+type ParamsError struct {
+	Fields []FieldError
+}
+
+func (e *ParamsError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return "invalid parameters: " + strings.Join(messages, "; ")
+}
+
+func (e *ParamsError) add(path string, message string) {
+	e.Fields = append(e.Fields, FieldError{Path: path, Message: message})
+}
+
+// Decodes the parameters into the struct T.
+//
+// The parameter names are taken from the `json` tag of the fields.
+// The fields without the tag are using the field name,
+// the fields with "-" tag are skipped.
+// The field is required unless the tag has the "omitempty" option.
+//
+//	type Request struct {
+//		NetworkId string   `json:"network_id"`
+//		Keys      []string `json:"keys,omitempty"`
+//	}
+//
+// The nested structs, pointers, slices and maps are decoded recursively.
+// The big.Int and big.Rat fields accept the same values as GetBigInt() and GetDecimal().
+// The map[string]interface{} and interface{} fields are set as is.
+//
+// Instead of stopping at the first invalid parameter, all of them are
+// returned in the *ParamsError.
+func DecodeParams[T any](parameters map[string]interface{}) (T, error) {
+	var result T
+
+	value := reflect.ValueOf(&result).Elem()
+	if value.Kind() != reflect.Struct {
+		return result, errors.New("DecodeParams expects a struct, not " + value.Kind().String())
+	}
+
+	params_error := &ParamsError{}
+	decode_struct(parameters, value, "", params_error)
+	if len(params_error.Fields) > 0 {
+		return result, params_error
+	}
+
+	return result, nil
+}
+
+// Encodes the struct into the parameters.
+// It's the reverse of DecodeParams() and uses the same tags.
+// The empty fields with the "omitempty" option are not included.
+func EncodeParams(object interface{}) (map[string]interface{}, error) {
+	value := reflect.ValueOf(object)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, errors.New("EncodeParams expects a struct, not nil")
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("EncodeParams expects a struct, not " + value.Kind().String())
+	}
+
+	return encode_struct(value)
+}
+
+// The parameter name of the struct field and whether its optional.
+// Returns empty name if the field should be skipped.
+func param_name(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag, tagged := field.Tag.Lookup("json")
+	if !tagged {
+		return field.Name, false
+	}
+	if tag == "-" {
+		return "", false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	optional := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			optional = true
+		}
+	}
+
+	return name, optional
+}
+
+func join_path(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func decode_struct(parameters map[string]interface{}, value reflect.Value, path string, params_error *ParamsError) {
+	value_type := value.Type()
+	for i := 0; i < value_type.NumField(); i++ {
+		field := value_type.Field(i)
+		name, optional := param_name(field)
+		if name == "" {
+			continue
+		}
+		field_path := join_path(path, name)
+
+		raw, exists := parameters[name]
+		if !exists || raw == nil {
+			if !optional {
+				params_error.add(field_path, "parameter is missing")
+			}
+			continue
+		}
+
+		decode_value(raw, value.Field(i), field_path, params_error)
+	}
+}
+
+var interface_type = reflect.TypeOf((*interface{})(nil)).Elem()
+
+func decode_value(raw interface{}, value reflect.Value, path string, params_error *ParamsError) {
+	switch value.Type() {
+	case interface_type:
+		value.Set(reflect.ValueOf(raw))
+		return
+	case big_int_type:
+		number, err := big_int_value(raw)
+		if err != nil {
+			params_error.add(path, err.Error())
+			return
+		}
+		value.Set(reflect.ValueOf(number).Elem())
+		return
+	case big_rat_type:
+		number, err := big_rat_value(raw)
+		if err != nil {
+			params_error.add(path, err.Error())
+			return
+		}
+		value.Set(reflect.ValueOf(number).Elem())
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer:
+		element := reflect.New(value.Type().Elem())
+		fields := len(params_error.Fields)
+		decode_value(raw, element.Elem(), path, params_error)
+		if len(params_error.Fields) == fields {
+			value.Set(element)
+		}
+	case reflect.String:
+		str, ok := raw.(string)
+		if !ok {
+			params_error.add(path, "expected to be a string")
+			return
+		}
+		value.SetString(str)
+	case reflect.Bool:
+		boolean, ok := raw.(bool)
+		if !ok {
+			params_error.add(path, "expected to be a boolean")
+			return
+		}
+		value.SetBool(boolean)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := uint64_value(raw)
+		if err != nil {
+			params_error.add(path, err.Error())
+			return
+		}
+		if value.OverflowUint(number) {
+			params_error.add(path, "the number "+strconv.FormatUint(number, 10)+" overflows "+value.Kind().String())
+			return
+		}
+		value.SetUint(number)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := int64_value(raw)
+		if err != nil {
+			params_error.add(path, err.Error())
+			return
+		}
+		if value.OverflowInt(number) {
+			params_error.add(path, "the number "+strconv.FormatInt(number, 10)+" overflows "+value.Kind().String())
+			return
+		}
+		value.SetInt(number)
+	case reflect.Float32, reflect.Float64:
+		number, err := float64_value(raw)
+		if err != nil {
+			params_error.add(path, err.Error())
+			return
+		}
+		value.SetFloat(number)
+	case reflect.Slice:
+		list := reflect.ValueOf(raw)
+		if list.Kind() != reflect.Slice {
+			params_error.add(path, "expected to be a list")
+			return
+		}
+		slice := reflect.MakeSlice(value.Type(), list.Len(), list.Len())
+		for i := 0; i < list.Len(); i++ {
+			decode_value(list.Index(i).Interface(), slice.Index(i), path+"["+strconv.Itoa(i)+"]", params_error)
+		}
+		value.Set(slice)
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			params_error.add(path, "unsupported map key type "+value.Type().Key().String())
+			return
+		}
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			params_error.add(path, "expected to be a map")
+			return
+		}
+		result := reflect.MakeMapWithSize(value.Type(), len(object))
+		for key, raw_element := range object {
+			element := reflect.New(value.Type().Elem()).Elem()
+			decode_value(raw_element, element, join_path(path, key), params_error)
+			result.SetMapIndex(reflect.ValueOf(key).Convert(value.Type().Key()), element)
+		}
+		value.Set(result)
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			params_error.add(path, "expected to be a map")
+			return
+		}
+		decode_struct(object, value, path, params_error)
+	default:
+		params_error.add(path, "unsupported field type "+value.Type().String())
+	}
+}
+
+func encode_struct(value reflect.Value) (map[string]interface{}, error) {
+	parameters := map[string]interface{}{}
+
+	value_type := value.Type()
+	for i := 0; i < value_type.NumField(); i++ {
+		field := value_type.Field(i)
+		name, optional := param_name(field)
+		if name == "" {
+			continue
+		}
+		field_value := value.Field(i)
+		if optional && field_value.IsZero() {
+			continue
+		}
+
+		encoded, err := encode_value(field_value)
+		if err != nil {
+			return nil, errors.New("'" + name + "' " + err.Error())
+		}
+		parameters[name] = encoded
+	}
+
+	return parameters, nil
+}
+
+var json_marshaler_type = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// The big numbers are encoded as the decimal strings, since JSON numbers lose the precision.
+//
+// The values with their own JSON encoding, for example json.RawMessage, and the bytes
+// are encoded as they would be by json.Marshal(). The bytes are the base64 string.
+func encode_value(value reflect.Value) (interface{}, error) {
+	switch value.Type() {
+	case big_int_type:
+		number := value.Interface().(big.Int)
+		return number.String(), nil
+	case big_rat_type:
+		number := value.Interface().(big.Rat)
+		return DecimalString(&number), nil
+	}
+
+	if value.Kind() != reflect.Interface {
+		if value.Type().Implements(json_marshaler_type) {
+			return encode_json(value.Interface())
+		}
+		if value.CanAddr() && reflect.PointerTo(value.Type()).Implements(json_marshaler_type) {
+			return encode_json(value.Addr().Interface())
+		}
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return encode_json(value.Interface())
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return encode_value(value.Elem())
+	case reflect.Struct:
+		return encode_struct(value)
+	case reflect.Slice:
+		if value.IsNil() {
+			return []interface{}{}, nil
+		}
+		list := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			encoded, err := encode_value(value.Index(i))
+			if err != nil {
+				return nil, errors.New("[" + strconv.Itoa(i) + "] " + err.Error())
+			}
+			list[i] = encoded
+		}
+		return list, nil
+	case reflect.Map:
+		object := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			encoded, err := encode_value(iter.Value())
+			if err != nil {
+				return nil, errors.New("'" + key + "' " + err.Error())
+			}
+			object[key] = encoded
+		}
+		return object, nil
+	default:
+		return value.Interface(), nil
+	}
+}
+
+// Encodes the value by json.Marshal() then decodes it into the generic value.
+// The numbers are decoded as json.Number to keep the precision.
+func encode_json(value interface{}) (interface{}, error) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.New("failed to encode as JSON: " + err.Error())
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, errors.New("failed to decode the JSON: " + err.Error())
+	}
+
+	return decoded, nil
+}
+
+// Converts the decoded number into uint64
+func uint64_value(raw interface{}) (uint64, error) {
+	switch value := raw.(type) {
+	case uint64:
+		return value, nil
+	case uint:
+		return uint64(value), nil
+	case uint32:
+		return uint64(value), nil
+	case uint16:
+		return uint64(value), nil
+	case uint8:
+		return uint64(value), nil
+	case int64:
+		return positive(value)
+	case int:
+		return positive(int64(value))
+	case int32:
+		return positive(int64(value))
+	case int16:
+		return positive(int64(value))
+	case int8:
+		return positive(int64(value))
+	case float64:
+		if value < 0 || value != math.Trunc(value) || value >= math.MaxUint64 {
+			return 0, errors.New("expected to be a positive integer")
+		}
+		return uint64(value), nil
+	case json.Number:
+		number, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return 0, errors.New("expected to be a positive integer")
+		}
+		return number, nil
+	default:
+		return 0, errors.New("expected to be a number")
+	}
+}
+
+func positive(value int64) (uint64, error) {
+	if value < 0 {
+		return 0, errors.New("expected to be a positive number")
+	}
+	return uint64(value), nil
+}
+
+// Converts the decoded number into int64
+func int64_value(raw interface{}) (int64, error) {
+	switch value := raw.(type) {
+	case int64:
+		return value, nil
+	case int:
+		return int64(value), nil
+	case int32:
+		return int64(value), nil
+	case int16:
+		return int64(value), nil
+	case int8:
+		return int64(value), nil
+	case uint64:
+		if value > math.MaxInt64 {
+			return 0, errors.New("the number overflows int64")
+		}
+		return int64(value), nil
+	case uint:
+		if uint64(value) > math.MaxInt64 {
+			return 0, errors.New("the number overflows int64")
+		}
+		return int64(value), nil
+	case uint32:
+		return int64(value), nil
+	case uint16:
+		return int64(value), nil
+	case uint8:
+		return int64(value), nil
+	case float64:
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return 0, errors.New("expected to be an integer")
+		}
+		return int64(value), nil
+	case json.Number:
+		number, err := value.Int64()
+		if err != nil {
+			return 0, errors.New("expected to be an integer")
+		}
+		return number, nil
+	default:
+		return 0, errors.New("expected to be a number")
+	}
+}
+
+// Converts the decoded number into float64
+func float64_value(raw interface{}) (float64, error) {
+	switch value := raw.(type) {
+	case float64:
+		return value, nil
+	case float32:
+		return float64(value), nil
+	case json.Number:
+		number, err := value.Float64()
+		if err != nil {
+			return 0, errors.New("expected to be a number")
+		}
+		return number, nil
+	default:
+		number, err := int64_value(raw)
+		if err == nil {
+			return float64(number), nil
+		}
+		unsigned, err := uint64_value(raw)
+		if err != nil {
+			return 0, errors.New("expected to be a number")
+		}
+		return float64(unsigned), nil
+	}
+}
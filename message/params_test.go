@@ -0,0 +1,341 @@
+package message
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type params_log struct {
+	Index uint   `json:"log_index"`
+	Name  string `json:"name,omitempty"`
+}
+
+type params_transaction struct {
+	Txid        string       `json:"txid"`
+	BlockNumber uint64       `json:"block_number"`
+	Value       big.Int      `json:"value"`
+	Fee         *big.Int     `json:"fee,omitempty"`
+	Price       big.Rat      `json:"price,omitempty"`
+	Logs        []params_log `json:"logs,omitempty"`
+}
+
+type params_request struct {
+	NetworkId    string               `json:"network_id"`
+	Confirmed    bool                 `json:"confirmed,omitempty"`
+	Limit        *int                 `json:"limit,omitempty"`
+	Keys         []string             `json:"keys,omitempty"`
+	Transactions []params_transaction `json:"transactions,omitempty"`
+	Arguments    map[string]string    `json:"arguments,omitempty"`
+	Skipped      string               `json:"-"`
+	Raw          interface{}          `json:"raw,omitempty"`
+	unexported   string
+}
+
+func TestDecodeParams(t *testing.T) {
+	limit := 10
+	vectors := []struct {
+		name       string
+		parameters map[string]interface{}
+		expected   params_request
+	}{
+		{
+			name:       "required only",
+			parameters: map[string]interface{}{"network_id": "1"},
+			expected:   params_request{NetworkId: "1"},
+		},
+		{
+			name: "extra fields are ignored",
+			parameters: map[string]interface{}{
+				"network_id": "1",
+				"unknown":    "x",
+				"Skipped":    "x",
+				"-":          "x",
+				"unexported": "x",
+			},
+			expected: params_request{NetworkId: "1"},
+		},
+		{
+			name:       "null is missing",
+			parameters: map[string]interface{}{"network_id": "1", "limit": nil},
+			expected:   params_request{NetworkId: "1"},
+		},
+		{
+			name: "pointer, slice and map",
+			parameters: map[string]interface{}{
+				"network_id": "1",
+				"confirmed":  true,
+				"limit":      float64(10),
+				"keys":       []interface{}{"a", "b"},
+				"arguments":  map[string]interface{}{"to": "0x0"},
+				"raw":        map[string]interface{}{"a": float64(1)},
+			},
+			expected: params_request{
+				NetworkId: "1",
+				Confirmed: true,
+				Limit:     &limit,
+				Keys:      []string{"a", "b"},
+				Arguments: map[string]string{"to": "0x0"},
+				Raw:       map[string]interface{}{"a": float64(1)},
+			},
+		},
+		{
+			name: "nested structs and big numbers",
+			parameters: map[string]interface{}{
+				"network_id": "1",
+				"transactions": []interface{}{
+					map[string]interface{}{
+						"txid":         "0x01",
+						"block_number": json.Number("18446744073709551615"),
+						"value":        "1000000000000000000000",
+						"fee":          "0x10",
+						"price":        "0.000000000000000001",
+						"logs": []interface{}{
+							map[string]interface{}{"log_index": float64(2), "name": "Transfer"},
+						},
+					},
+					map[string]interface{}{
+						"txid":         "0x02",
+						"block_number": float64(5),
+						"value":        "1e18",
+					},
+				},
+			},
+			expected: params_request{
+				NetworkId: "1",
+				Transactions: []params_transaction{
+					{
+						Txid:        "0x01",
+						BlockNumber: 18446744073709551615,
+						Value:       *big_int("1000000000000000000000"),
+						Fee:         big.NewInt(16),
+						Price:       *big.NewRat(1, 1000000000000000000),
+						Logs:        []params_log{{Index: 2, Name: "Transfer"}},
+					},
+					{
+						Txid:        "0x02",
+						BlockNumber: 5,
+						Value:       *big_int("1000000000000000000"),
+					},
+				},
+			},
+		},
+	}
+
+	for _, vector := range vectors {
+		decoded, err := DecodeParams[params_request](vector.parameters)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", vector.name, err)
+			continue
+		}
+		if !equal_requests(decoded, vector.expected) {
+			t.Errorf("%s:\n got: %+v\nwant: %+v", vector.name, decoded, vector.expected)
+		}
+	}
+}
+
+func TestDecodeParamsErrors(t *testing.T) {
+	vectors := []struct {
+		name       string
+		parameters map[string]interface{}
+		fields     []FieldError
+	}{
+		{
+			name:       "missing required",
+			parameters: map[string]interface{}{},
+			fields:     []FieldError{{"network_id", "parameter is missing"}},
+		},
+		{
+			name:       "wrong types",
+			parameters: map[string]interface{}{"network_id": float64(1), "confirmed": "yes", "keys": "a"},
+			fields: []FieldError{
+				{"network_id", "expected to be a string"},
+				{"confirmed", "expected to be a boolean"},
+				{"keys", "expected to be a list"},
+			},
+		},
+		{
+			name: "nested paths",
+			parameters: map[string]interface{}{
+				"network_id": "1",
+				"keys":       []interface{}{"a", float64(1)},
+				"arguments":  map[string]interface{}{"to": true},
+				"transactions": []interface{}{
+					map[string]interface{}{"txid": "0x01", "block_number": float64(1), "value": "1"},
+					map[string]interface{}{
+						"block_number": float64(-1),
+						"value":        "1.5",
+						"fee":          "0xzz",
+						"logs":         []interface{}{map[string]interface{}{}},
+					},
+					"not a map",
+				},
+			},
+			fields: []FieldError{
+				{"keys[1]", "expected to be a string"},
+				{"transactions[1].txid", "parameter is missing"},
+				{"transactions[1].block_number", "expected to be a positive integer"},
+				{"transactions[1].value", "expected to be an integer, got '1.5'"},
+				{"transactions[1].fee", "invalid hex number '0xzz'"},
+				{"transactions[1].logs[0].log_index", "parameter is missing"},
+				{"transactions[2]", "expected to be a map"},
+				{"arguments.to", "expected to be a string"},
+			},
+		},
+		{
+			name:       "overflow",
+			parameters: map[string]interface{}{"network_id": "1", "transactions": []interface{}{map[string]interface{}{"txid": "0x01", "block_number": "1", "value": "1e79"}}},
+			fields: []FieldError{
+				{"transactions[0].block_number", "expected to be a number"},
+				{"transactions[0].value", "the exponent of the number '1e79' exceeds 78"},
+			},
+		},
+	}
+
+	for _, vector := range vectors {
+		_, err := DecodeParams[params_request](vector.parameters)
+		params_error, ok := err.(*ParamsError)
+		if !ok {
+			t.Errorf("%s: expected *ParamsError, got %v", vector.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(params_error.Fields, vector.fields) {
+			t.Errorf("%s:\n got: %v\nwant: %v", vector.name, params_error.Fields, vector.fields)
+		}
+	}
+}
+
+func TestParamsErrorMessage(t *testing.T) {
+	params_error := &ParamsError{}
+	params_error.add("network_id", "parameter is missing")
+	params_error.add("transactions[0].txid", "expected to be a string")
+
+	expected := "invalid parameters: 'network_id' parameter is missing; 'transactions[0].txid' expected to be a string"
+	if params_error.Error() != expected {
+		t.Errorf("got: %s\nwant: %s", params_error.Error(), expected)
+	}
+}
+
+func TestDecodeParamsNotStruct(t *testing.T) {
+	if _, err := DecodeParams[string](map[string]interface{}{}); err == nil {
+		t.Error("expected an error for the non struct type")
+	}
+}
+
+func TestEncodeParams(t *testing.T) {
+	limit := 10
+	request := params_request{
+		NetworkId: "1",
+		Limit:     &limit,
+		Keys:      []string{"a"},
+		Transactions: []params_transaction{
+			{
+				Txid:        "0x01",
+				BlockNumber: 18446744073709551615,
+				Value:       *big_int("1000000000000000000000"),
+				Price:       *big.NewRat(1, 4),
+				Logs:        []params_log{{Index: 2}},
+			},
+		},
+		Skipped: "x",
+	}
+
+	expected := map[string]interface{}{
+		"network_id": "1",
+		"limit":      10,
+		"keys":       []interface{}{"a"},
+		"transactions": []interface{}{
+			map[string]interface{}{
+				"txid":         "0x01",
+				"block_number": uint64(18446744073709551615),
+				"value":        "1000000000000000000000",
+				"price":        "0.25",
+				"logs":         []interface{}{map[string]interface{}{"log_index": uint(2)}},
+			},
+		},
+	}
+
+	for _, object := range []interface{}{request, &request} {
+		parameters, err := EncodeParams(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parameters, expected) {
+			t.Errorf("\n got: %v\nwant: %v", parameters, expected)
+		}
+	}
+
+	var nil_request *params_request
+	for _, object := range []interface{}{nil_request, "string", 1} {
+		if _, err := EncodeParams(object); err == nil {
+			t.Errorf("expected an error for %v", object)
+		}
+	}
+}
+
+func TestEncodeDecodeParams(t *testing.T) {
+	limit := 0
+	request := params_request{
+		NetworkId: "1",
+		Confirmed: true,
+		Limit:     &limit,
+		Keys:      []string{"a", "b"},
+		Transactions: []params_transaction{
+			{
+				Txid:        "0x01",
+				BlockNumber: 18446744073709551615,
+				Value:       *big_int("-115792089237316195423570985008687907853269984665640564039457584007913129639935"),
+				Fee:         big.NewInt(0),
+				Price:       *big.NewRat(-3, 8),
+			},
+		},
+		Arguments: map[string]string{"to": "0x0"},
+	}
+
+	parameters, err := EncodeParams(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeParams[params_request](parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal_requests(decoded, request) {
+		t.Errorf("\n got: %+v\nwant: %+v", decoded, request)
+	}
+}
+
+func big_int(str string) *big.Int {
+	number, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		panic("invalid number " + str)
+	}
+	return number
+}
+
+// The big numbers are compared by value, since reflect.DeepEqual compares their internals.
+func equal_requests(a params_request, b params_request) bool {
+	a_transactions, b_transactions := a.Transactions, b.Transactions
+	a.Transactions, b.Transactions = nil, nil
+	if !reflect.DeepEqual(a, b) || len(a_transactions) != len(b_transactions) {
+		return false
+	}
+
+	for i := range a_transactions {
+		x, y := a_transactions[i], b_transactions[i]
+		if x.Value.Cmp(&y.Value) != 0 || x.Price.Cmp(&y.Price) != 0 {
+			return false
+		}
+		if (x.Fee == nil) != (y.Fee == nil) || (x.Fee != nil && x.Fee.Cmp(y.Fee) != 0) {
+			return false
+		}
+		x.Value, y.Value = big.Int{}, big.Int{}
+		x.Price, y.Price = big.Rat{}, big.Rat{}
+		x.Fee, y.Fee = nil, nil
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
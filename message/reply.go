@@ -3,13 +3,18 @@ package message
 import (
 	"encoding/json"
 	"errors"
-	"strings"
 )
 
 // SDS Service returns the reply. Anyone who sends a request to the SDS Service gets this message.
+//
+// The failure reply could have the error code and the details.
+// See FailWithCode().
 type Reply struct {
+	Header  Header // optional, the header of the request
 	Status  string
 	Message string
+	Code    ErrorCode              // optional, set only for the failures
+	Details map[string]interface{} // optional, the data of the failure
 	Params  map[string]interface{}
 }
 
@@ -24,11 +29,21 @@ func (r *Reply) IsOK() bool { return r.Status == "OK" }
 
 // Convert to JSON
 func (reply *Reply) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	i := map[string]interface{}{
 		"status":  reply.Status,
 		"message": reply.Message,
 		"params":  reply.Params,
 	}
+	if !reply.Header.IsEmpty() {
+		i["header"] = reply.Header.ToJSON()
+	}
+	if reply.Code != "" {
+		i["code"] = string(reply.Code)
+	}
+	if len(reply.Details) > 0 {
+		i["details"] = reply.Details
+	}
+	return i
 }
 
 // Convert the reply to the string format
@@ -49,13 +64,8 @@ func (reply *Reply) ToBytes() []byte {
 
 // Zeromq received raw strings converted to the Reply message.
 func ParseReply(msgs []string) (Reply, error) {
-	msg := ToString(msgs)
-	var dat map[string]interface{}
-
-	decoder := json.NewDecoder(strings.NewReader(msg))
-	decoder.UseNumber()
-
-	if err := decoder.Decode(&dat); err != nil {
+	dat, err := DecodeFrames(msgs)
+	if err != nil {
 		return Reply{}, err
 	}
 
@@ -89,5 +99,27 @@ func ParseJsonReply(dat map[string]interface{}) (Reply, error) {
 		reply.Params = parameters
 	}
 
+	header, err := ParseHeader(dat)
+	if err != nil {
+		return reply, err
+	}
+	reply.Header = header
+
+	// the older services don't set the code and details
+	if _, exists := dat["code"]; exists {
+		code, err := GetString(dat, "code")
+		if err != nil {
+			return reply, err
+		}
+		reply.Code = ErrorCode(code)
+	}
+	if _, exists := dat["details"]; exists {
+		details, err := GetMap(dat, "details")
+		if err != nil {
+			return reply, err
+		}
+		reply.Details = details
+	}
+
 	return reply, nil
 }
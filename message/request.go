@@ -3,27 +3,35 @@ package message
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 // The SDS Service will accepts the Request message.
 type Request struct {
+	Header     Header // optional
 	Command    string
 	Parameters map[string]interface{}
 }
 
 // Convert Request to JSON
 func (request *Request) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	i := map[string]interface{}{
 		"command":    request.Command,
 		"parameters": request.Parameters,
 	}
+	if !request.Header.IsEmpty() {
+		i["header"] = request.Header.ToJSON()
+	}
+	return i
 }
 
 func (request *Request) CommandName() string {
 	return request.Command
 }
 
+func (request *Request) RequestHeader() Header {
+	return request.Header
+}
+
 // Request message as a  sequence of bytes
 func (reply *Request) ToBytes() []byte {
 	interfaces := reply.ToJSON()
@@ -52,14 +60,8 @@ func ToString(msgs []string) string {
 
 // Parse the messages from zeromq into the Request
 func ParseRequest(msgs []string) (Request, error) {
-	msg := ToString(msgs)
-
-	var dat map[string]interface{}
-
-	decoder := json.NewDecoder(strings.NewReader(msg))
-	decoder.UseNumber()
-
-	if err := decoder.Decode(&dat); err != nil {
+	dat, err := DecodeFrames(msgs)
+	if err != nil {
 		return Request{}, err
 	}
 
@@ -71,8 +73,13 @@ func ParseRequest(msgs []string) (Request, error) {
 	if err != nil {
 		return Request{}, err
 	}
+	header, err := ParseHeader(dat)
+	if err != nil {
+		return Request{}, err
+	}
 
 	request := Request{
+		Header:     header,
 		Command:    command,
 		Parameters: parameters,
 	}
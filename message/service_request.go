@@ -3,13 +3,13 @@ package message
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	"github.com/blocklords/gosds/env"
 )
 
 // The SDS Service will accepts a request from another request
 type ServiceRequest struct {
+	Header     Header                 // optional
 	Service    *env.Env               // The service parameters
 	Command    string                 // Command type
 	Parameters map[string]interface{} // Parameters of the request
@@ -19,13 +19,21 @@ func (request *ServiceRequest) CommandName() string {
 	return request.Command
 }
 
+func (request *ServiceRequest) RequestHeader() Header {
+	return request.Header
+}
+
 // Convert ServiceRequest to JSON
 func (request *ServiceRequest) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	i := map[string]interface{}{
 		"public_key": request.Service.PublicKey(),
 		"command":    request.Command,
 		"parameters": request.Parameters,
 	}
+	if !request.Header.IsEmpty() {
+		i["header"] = request.Header.ToJSON()
+	}
+	return i
 }
 
 // ServiceRequest message as a  sequence of bytes
@@ -47,14 +55,8 @@ func (request *ServiceRequest) ToString() string {
 
 // Parse the messages from zeromq into the ServiceRequest
 func ParseServiceRequest(msgs []string) (ServiceRequest, error) {
-	msg := ToString(msgs)
-
-	var dat map[string]interface{}
-
-	decoder := json.NewDecoder(strings.NewReader(msg))
-	decoder.UseNumber()
-
-	if err := decoder.Decode(&dat); err != nil {
+	dat, err := DecodeFrames(msgs)
+	if err != nil {
 		return ServiceRequest{}, err
 	}
 
@@ -71,6 +73,10 @@ func ParseServiceRequest(msgs []string) (ServiceRequest, error) {
 	if err != nil {
 		return ServiceRequest{}, err
 	}
+	header, err := ParseHeader(dat)
+	if err != nil {
+		return ServiceRequest{}, err
+	}
 
 	// The developers or smartcontract developer public keys are not in the environment variable
 	// as a servie.
@@ -80,6 +86,7 @@ func ParseServiceRequest(msgs []string) (ServiceRequest, error) {
 	}
 
 	request := ServiceRequest{
+		Header:     header,
 		Service:    service_env,
 		Command:    command,
 		Parameters: parameters,
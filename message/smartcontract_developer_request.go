@@ -1,31 +1,68 @@
 package message
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// The signature schemes of the SmartcontractDeveloperRequest.
+// The SDS Service verifies the signature by the scheme, see account.NewSmartcontractDeveloper().
+const (
+	// The default. The wallet signs the hash of the canonical JSON, see DigestedMessage().
+	PERSONAL_SIGN_SCHEME = "personal_sign"
+	// The wallet signs the EIP-712 typed data, and shows the command and parameters to the user.
+	// See Eip712TypedData().
+	EIP712_SCHEME = "eip712"
+	// The ed25519 key signs the hash of the canonical JSON.
+	// The address is the hex of the 32 bytes public key.
+	ED25519_SCHEME = "ed25519"
+	// The address is a contract wallet, that validates the signature of the EIP-712 digest
+	// by the isValidSignature() method. See https://eips.ethereum.org/EIPS/eip-1271
+	EIP1271_SCHEME = "eip1271"
+)
+
 // The SDS Service will accepts the SmartcontractDeveloperRequest message.
 type SmartcontractDeveloperRequest struct {
-	Address        string                 // The whitelisted address of the user
-	NonceTimestamp uint64                 // Nonce as a unix timestamp in seconds
-	Signature      string                 // Command, nonce, address and parameters signed together
-	Command        string                 // Command type
-	Parameters     map[string]interface{} // Parameters of the request
+	Header          Header                 // optional, not signed
+	Address         string                 // The whitelisted address of the user
+	NonceTimestamp  uint64                 // Nonce as a unix timestamp in seconds
+	Signature       string                 // Command, nonce, address and parameters signed together
+	SignatureScheme string                 // optional, PERSONAL_SIGN_SCHEME if empty
+	Command         string                 // Command type
+	Parameters      map[string]interface{} // Parameters of the request
 }
 
 // Convert SmartcontractDeveloperRequest to JSON
 func (request *SmartcontractDeveloperRequest) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	i := map[string]interface{}{
 		"address":         request.Address,
 		"nonce_timestamp": request.NonceTimestamp,
 		"signature":       request.Signature,
 		"command":         request.Command,
 		"parameters":      request.Parameters,
 	}
+	if !request.Header.IsEmpty() {
+		i["header"] = request.Header.ToJSON()
+	}
+	if request.SignatureScheme != "" {
+		i["signature_scheme"] = request.SignatureScheme
+	}
+	return i
+}
+
+func (request *SmartcontractDeveloperRequest) CommandName() string {
+	return request.Command
+}
+
+func (request *SmartcontractDeveloperRequest) RequestHeader() Header {
+	return request.Header
 }
 
 // SmartcontractDeveloperRequest message as a  sequence of bytes
@@ -46,14 +83,16 @@ func (request *SmartcontractDeveloperRequest) ToString() string {
 }
 
 // Gets the message without a prefix.
-// The message is a JSON represantion of the Request but without "signature" parameter.
+// The message is a JSON represantion of the Request but without "signature", "signature_scheme" and "header" parameters.
 // Converted into the hash using Keccak32.
 //
-// The request parameters are oredered in an alphanumerical order.
+// The JSON is encoded by CanonicalJSON(), so that the clients in any language hash the same bytes.
 func (request *SmartcontractDeveloperRequest) message_hash() []byte {
 	json_object := request.ToJSON()
 	delete(json_object, "signature")
-	bytes, err := json.Marshal(json_object)
+	delete(json_object, "signature_scheme")
+	delete(json_object, "header")
+	bytes, err := CanonicalJSON(json_object)
 	if err != nil {
 		fmt.Println("error while converting json into bytes", err)
 		return []byte{}
@@ -66,23 +105,90 @@ func (request *SmartcontractDeveloperRequest) message_hash() []byte {
 
 // Gets the digested message with a prefix
 // For ethereum the prefix is "\x19Ethereum Signed Message:\n"
+//
+// If the request is signed by EIP712_SCHEME or EIP1271_SCHEME, then it's the EIP-712 digest
+// of the request in the domain returned by GetEip712Domain().
+// If the request is signed by ED25519_SCHEME, then it's the message hash without the prefix.
 func (request *SmartcontractDeveloperRequest) DigestedMessage() []byte {
+	switch request.SignatureScheme {
+	case EIP712_SCHEME, EIP1271_SCHEME:
+		return request.eip712_digest(GetEip712Domain())
+	case ED25519_SCHEME:
+		return request.message_hash()
+	}
+
 	message_hash := request.message_hash()
 	prefix := []byte("\x19Ethereum Signed Message:\n32")
 	digested_hash := crypto.Keccak256Hash(append(prefix, message_hash...))
 	return digested_hash.Bytes()
 }
 
-// Parse the messages from zeromq into the SmartcontractDeveloperRequest
-func ParseSmartcontractDeveloperRequest(msgs []string) (SmartcontractDeveloperRequest, error) {
-	msg := ToString(msgs)
+// Signs the request by the ECDSA private key.
+//
+// The address is set to the address of the private key.
+// If the nonce timestamp is not set, then the current time is used.
+// The signature is in the Ethereum format, with V as 27 or 28.
+//
+// The request is signed according to its SignatureScheme,
+// either PERSONAL_SIGN_SCHEME or EIP712_SCHEME.
+func (request *SmartcontractDeveloperRequest) Sign(private_key *ecdsa.PrivateKey) error {
+	if private_key == nil {
+		return fmt.Errorf("the private key is nil")
+	}
+	switch request.SignatureScheme {
+	case "", PERSONAL_SIGN_SCHEME, EIP712_SCHEME:
+	default:
+		return errors.New("the signature scheme '" + request.SignatureScheme + "' is not signed by the ECDSA key")
+	}
+
+	request.Address = crypto.PubkeyToAddress(private_key.PublicKey).Hex()
+	if request.NonceTimestamp == 0 {
+		request.NonceTimestamp = uint64(time.Now().Unix())
+	}
+
+	digested_hash := request.DigestedMessage()
+	if len(digested_hash) == 0 {
+		return errors.New("failed to digest the request")
+	}
+	signature, err := crypto.Sign(digested_hash, private_key)
+	if err != nil {
+		return err
+	}
+	signature[64] += 27 // Transform V from 0/1 to yellow paper 27/28
+
+	request.Signature = hexutil.Encode(signature)
 
-	var dat map[string]interface{}
+	return nil
+}
 
-	decoder := json.NewDecoder(strings.NewReader(msg))
-	decoder.UseNumber()
+// Signs the request by the ed25519 private key with ED25519_SCHEME.
+//
+// The address is set to the hex of the public key.
+// If the nonce timestamp is not set, then the current time is used.
+func (request *SmartcontractDeveloperRequest) SignEd25519(private_key ed25519.PrivateKey) error {
+	if len(private_key) != ed25519.PrivateKeySize {
+		return errors.New("invalid ed25519 private key")
+	}
 
-	if err := decoder.Decode(&dat); err != nil {
+	request.SignatureScheme = ED25519_SCHEME
+	request.Address = hexutil.Encode(private_key.Public().(ed25519.PublicKey))
+	if request.NonceTimestamp == 0 {
+		request.NonceTimestamp = uint64(time.Now().Unix())
+	}
+
+	digested_hash := request.DigestedMessage()
+	if len(digested_hash) == 0 {
+		return errors.New("failed to digest the request")
+	}
+	request.Signature = hexutil.Encode(ed25519.Sign(private_key, digested_hash))
+
+	return nil
+}
+
+// Parse the messages from zeromq into the SmartcontractDeveloperRequest
+func ParseSmartcontractDeveloperRequest(msgs []string) (SmartcontractDeveloperRequest, error) {
+	dat, err := DecodeFrames(msgs)
+	if err != nil {
 		return SmartcontractDeveloperRequest{}, err
 	}
 
@@ -110,12 +216,27 @@ func ParseSmartcontractDeveloperRequest(msgs []string) (SmartcontractDeveloperRe
 		return SmartcontractDeveloperRequest{}, err
 	}
 
+	signature_scheme := ""
+	if _, ok := dat["signature_scheme"]; ok {
+		signature_scheme, err = GetString(dat, "signature_scheme")
+		if err != nil {
+			return SmartcontractDeveloperRequest{}, err
+		}
+	}
+
+	header, err := ParseHeader(dat)
+	if err != nil {
+		return SmartcontractDeveloperRequest{}, err
+	}
+
 	request := SmartcontractDeveloperRequest{
-		Address:        address,
-		NonceTimestamp: nonce_timestamp,
-		Signature:      signature,
-		Command:        command,
-		Parameters:     parameters,
+		Header:          header,
+		Address:         address,
+		NonceTimestamp:  nonce_timestamp,
+		Signature:       signature,
+		SignatureScheme: signature_scheme,
+		Command:         command,
+		Parameters:      parameters,
 	}
 
 	return request, nil
@@ -0,0 +1,71 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blocklords/gosds/message"
+)
+
+// The failure reply of the remote service as an error.
+// The request functions return it, when the remote service replied with a failure.
+//
+//	var remote_error *remote.RemoteError
+//	if errors.As(err, &remote_error) && remote_error.Code == message.NOT_FOUND {
+//		...
+//	}
+//
+// It also matches ErrRemoteFailure by errors.Is().
+type RemoteError struct {
+	Service string                 // the name of the remote service
+	Command string                 // the command that failed
+	Code    message.ErrorCode      // empty if the remote service didn't set it
+	Message string                 // the failure message of the reply
+	Details map[string]interface{} // optional details of the failure
+}
+
+// Creates the error from the failure reply
+func NewRemoteError(service string, command string, reply *message.Reply) *RemoteError {
+	return &RemoteError{
+		Service: service,
+		Command: command,
+		Code:    reply.Code,
+		Message: reply.Message,
+		Details: reply.Details,
+	}
+}
+
+func (e *RemoteError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s: the command '%s' replied with a failure by '%s'. the reply error message: %s", ErrRemoteFailure, e.Command, e.Service, e.Message)
+	}
+	return fmt.Sprintf("%s: the command '%s' replied with a failure %s by '%s'. the reply error message: %s", ErrRemoteFailure, e.Command, e.Code, e.Service, e.Message)
+}
+
+func (e *RemoteError) Unwrap() error {
+	return ErrRemoteFailure
+}
+
+// Whether the remote service replied with the error code
+func (e *RemoteError) Is(target error) bool {
+	other, ok := target.(*RemoteError)
+	if !ok {
+		return false
+	}
+	return other.Code != "" && other.Code == e.Code
+}
+
+// Converts the error returned by the request functions into the failure reply.
+// The code and details of the *RemoteError are kept, the timeout gets the message.TIMEOUT code.
+func ErrorReply(err error) message.Reply {
+	var remote_error *RemoteError
+	if errors.As(err, &remote_error) {
+		reply := message.FailWithCode(remote_error.Code, err.Error())
+		reply.Details = remote_error.Details
+		return reply
+	}
+	if errors.Is(err, ErrTimeout) {
+		return message.FailWithCode(message.TIMEOUT, err.Error())
+	}
+	return message.Fail(err.Error())
+}
@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/blocklords/gosds/env"
+	"github.com/blocklords/gosds/message"
+)
+
+// The Requester sends the requests to the remote SDS service.
+// It's implemented by a single Socket and by a Pool of the sockets.
+//
+// The data types that interact with the remote services accept the Requester,
+// so they could be used with both.
+type Requester interface {
+	RequestRemoteService(request *message.Request) (map[string]interface{}, error)
+	RequestRemoteServiceContext(ctx context.Context, request *message.Request) (map[string]interface{}, error)
+	RequestSmartcontractDeveloperContext(ctx context.Context, request *message.SmartcontractDeveloperRequest) (map[string]interface{}, error)
+}
+
+// The Pool keeps the request sockets connected to the same remote SDS service.
+//
+// A single Socket wraps zmq REQ socket, that can have only one request in flight.
+// The Pool lends a free socket for every request, therefore many requests
+// could be sent in parallel. The Pool is safe for concurrent use.
+//
+// The socket is used only by one goroutine at the same time,
+// so the reply is always received by the goroutine that sent the request.
+type Pool struct {
+	remoteService *env.Env
+	sockets       chan *Socket // free sockets
+	size          int
+	mu            sync.Mutex
+	retry_policy  RetryPolicy
+	codec         message.Codec
+	closed        bool
+}
+
+// The default amount of the sockets in the Pool
+const DEFAULT_POOL_SIZE = 4
+
+// Create a new Pool of the sockets on TCP protocol.
+// The size is the maximum amount of parallel requests.
+func NewTcpRequestPool(e *env.Env, client *env.Env, size int) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("the pool size should be atleast 1")
+	}
+	if !e.UrlExist() {
+		return nil, fmt.Errorf("missing .env variable: Please set '" + e.ServiceName() + "' host and port and curve key if security was enabled")
+	}
+
+	pool := Pool{
+		remoteService: e,
+		sockets:       make(chan *Socket, size),
+		size:          size,
+		retry_policy:  DefaultRetryPolicy(),
+		codec:         message.JsonCodec,
+	}
+
+	for i := 0; i < size; i++ {
+		socket, err := new_tcp_request_socket(e, client)
+		if err != nil {
+			// close the sockets that were already created
+			close(pool.sockets)
+			for created := range pool.sockets {
+				created.Close()
+			}
+			return nil, err
+		}
+		pool.sockets <- socket
+	}
+
+	return &pool, nil
+}
+
+// Create a new Pool of the sockets on TCP protocol otherwise exit from the program.
+func TcpRequestPoolOrPanic(e *env.Env, client *env.Env, size int) *Pool {
+	pool, err := NewTcpRequestPool(e, client, size)
+	if err != nil {
+		panic(err)
+	}
+
+	return pool
+}
+
+// Amount of the sockets in the pool
+func (pool *Pool) Size() int {
+	return pool.size
+}
+
+// Returns the HOST envrionment parameters of the pool.
+func (pool *Pool) RemoteEnv() *env.Env {
+	return pool.remoteService
+}
+
+// Sets the retry policy for all sockets in the pool.
+// The sockets that are in use will get the policy for their next request.
+func (pool *Pool) SetRetryPolicy(policy RetryPolicy) {
+	pool.mu.Lock()
+	pool.retry_policy = policy
+	pool.mu.Unlock()
+}
+
+// Sets the codec for all sockets in the pool.
+// The sockets that are in use will get the codec for their next request.
+func (pool *Pool) SetCodec(codec message.Codec) {
+	pool.mu.Lock()
+	pool.codec = codec
+	pool.mu.Unlock()
+}
+
+// Send a command to the remote SDS service using a free socket.
+// If all sockets are busy, then waits for the first released one.
+//
+// See Socket.RequestRemoteService()
+func (pool *Pool) RequestRemoteService(request *message.Request) (map[string]interface{}, error) {
+	socket, err := pool.acquire(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(socket)
+
+	return socket.RequestRemoteService(request)
+}
+
+// Send a command to the remote SDS service using a free socket.
+// The waiting for the free socket also stops if the context is done.
+//
+// See Socket.RequestRemoteServiceContext()
+func (pool *Pool) RequestRemoteServiceContext(ctx context.Context, request *message.Request) (map[string]interface{}, error) {
+	socket, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(socket)
+
+	return socket.RequestRemoteServiceContext(ctx, request)
+}
+
+// Send a signed command to the remote SDS service using a free socket.
+//
+// See Socket.RequestSmartcontractDeveloperContext()
+func (pool *Pool) RequestSmartcontractDeveloperContext(ctx context.Context, request *message.SmartcontractDeveloperRequest) (map[string]interface{}, error) {
+	socket, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(socket)
+
+	return socket.RequestSmartcontractDeveloperContext(ctx, request)
+}
+
+// Requests a message to the remote service using a free socket of the pool.
+//
+// See RequestReply()
+func PoolRequestReply[V SDS_Message](ctx context.Context, pool *Pool, request V) (map[string]interface{}, error) {
+	socket, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(socket)
+
+	return RequestReplyContext(ctx, socket, request)
+}
+
+// Close all sockets of the pool.
+// It waits until the sockets that are in use are released.
+func (pool *Pool) Close() error {
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return nil
+	}
+	pool.closed = true
+	pool.mu.Unlock()
+
+	var close_err error
+	for i := 0; i < pool.size; i++ {
+		socket := <-pool.sockets
+		if err := socket.Close(); err != nil && close_err == nil {
+			close_err = err
+		}
+	}
+	close(pool.sockets)
+
+	return close_err
+}
+
+// Take the free socket from the pool
+func (pool *Pool) acquire(ctx context.Context) (*Socket, error) {
+	pool.mu.Lock()
+	closed := pool.closed
+	policy := pool.retry_policy
+	codec := pool.codec
+	pool.mu.Unlock()
+	if closed {
+		return nil, errors.New("the pool of '" + pool.remoteService.ServiceName() + "' sockets is closed")
+	}
+
+	select {
+	case socket, ok := <-pool.sockets:
+		if !ok {
+			return nil, errors.New("the pool of '" + pool.remoteService.ServiceName() + "' sockets is closed")
+		}
+		socket.SetRetryPolicy(policy)
+		socket.SetCodec(codec)
+		return socket, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: no free socket to '%s' before the deadline", ErrTimeout, pool.remoteService.ServiceName())
+		}
+		return nil, fmt.Errorf("%w: no free socket to '%s': %s", ErrCancelled, pool.remoteService.ServiceName(), ctx.Err().Error())
+	}
+}
+
+// Return the socket back to the pool
+func (pool *Pool) release(socket *Socket) {
+	pool.sockets <- socket
+}
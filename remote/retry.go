@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// The errors returned by the context aware request-reply functions.
+// Use errors.Is() to check the reason of the failure.
+var (
+	ErrTimeout       = errors.New("remote service didn't reply in time")
+	ErrRemoteFailure = errors.New("remote service replied with a failure")
+	ErrCancelled     = errors.New("request was cancelled")
+)
+
+// How often the socket checks the context while waiting for the reply.
+const poll_interval = 100 * time.Millisecond
+
+// The RetryPolicy defines how many times the request is resent
+// if the remote service didn't reply within the request timeout.
+//
+// Between the attempts the socket waits with an exponential backoff:
+// InitialBackoff, InitialBackoff * 2, InitialBackoff * 4 and so on, but never more than MaxBackoff.
+// The Jitter is the fraction of the backoff that is randomized, to avoid
+// all clients reconnecting at the same time.
+type RetryPolicy struct {
+	MaxAttempts    uint          // 0 means retry until the context is done
+	InitialBackoff time.Duration // the delay after the first failed attempt
+	MaxBackoff     time.Duration // the delay will never exceed this value
+	Jitter         float64       // between 0 and 1
+
+	// optional, called before the request is resent.
+	// The attempt is the amount of the failed attempts, the err is the reason.
+	OnRetry func(attempt uint, err error)
+}
+
+// The retry policy used by the sockets unless another one was set by Socket.SetRetryPolicy()
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+// Whether the request could be sent again after the given amount of attempts
+func (policy RetryPolicy) CanRetry(attempts uint) bool {
+	return policy.MaxAttempts == 0 || attempts < policy.MaxAttempts
+}
+
+// Notifies the OnRetry function, if it's set.
+func (policy RetryPolicy) notify_retry(attempt uint, err error) {
+	if policy.OnRetry != nil {
+		policy.OnRetry(attempt, err)
+	}
+}
+
+// The delay before the next attempt.
+// The attempt is the amount of already failed attempts, starting from 1.
+func (policy RetryPolicy) Backoff(attempt uint) time.Duration {
+	if policy.InitialBackoff <= 0 || attempt == 0 {
+		return 0
+	}
+
+	backoff := policy.InitialBackoff
+	for i := uint(1); i < attempt; i++ {
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+			break
+		}
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		// randomize in the range of [backoff - jitter, backoff + jitter]
+		delta := float64(backoff) * jitter
+		backoff = time.Duration(float64(backoff) - delta + rand.Float64()*2*delta)
+	}
+
+	return backoff
+}
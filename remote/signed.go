@@ -0,0 +1,49 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/blocklords/gosds/message"
+)
+
+// Signs the request and sends it, while no other request of the signer is signed.
+// It's implemented by account.SmartcontractDeveloper.
+type Signer interface {
+	SignAndSend(request *message.SmartcontractDeveloperRequest, send func(*message.SmartcontractDeveloperRequest) error) error
+}
+
+// Signs the request by the signer, then sends it by the requester.
+//
+// If the request wasn't replied in time, then it's signed again with a fresh nonce and resent,
+// as long as the retry policy allows it. Since every attempt has its own nonce, the request
+// could be executed more than once. Pass RetryPolicy{MaxAttempts: 1} for the commands that
+// should not be repeated, for example the smartcontract writes.
+func RequestSigned(ctx context.Context, requester Requester, signer Signer, request *message.SmartcontractDeveloperRequest, policy RetryPolicy) (map[string]interface{}, error) {
+	var attempt uint = 0
+	for {
+		attempt++
+
+		var params map[string]interface{}
+		err := signer.SignAndSend(request, func(signed *message.SmartcontractDeveloperRequest) error {
+			var err error
+			params, err = requester.RequestSmartcontractDeveloperContext(ctx, signed)
+			return err
+		})
+		if err == nil {
+			return params, nil
+		}
+		if !errors.Is(err, ErrTimeout) || ctx.Err() != nil || !policy.CanRetry(attempt) {
+			return nil, err
+		}
+		policy.notify_retry(attempt, err)
+
+		backoff := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			backoff.Stop()
+		case <-backoff.C:
+		}
+	}
+}
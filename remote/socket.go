@@ -10,6 +10,7 @@
 package remote
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -30,12 +31,16 @@ type Socket struct {
 	thisService   *env.Env
 	poller        *zmq.Poller
 	socket        *zmq.Socket
+	retry_policy  RetryPolicy   // used by the context aware requests
+	codec         message.Codec // encodes the requests, the reply is decoded by the codec chosen by the remote service
 }
 
 type SDS_Message interface {
-	*message.Request | *message.ServiceRequest
+	*message.Request | *message.ServiceRequest | *message.SmartcontractDeveloperRequest
 
 	CommandName() string
+	RequestHeader() message.Header
+	ToJSON() map[string]interface{}
 	ToString() string
 }
 
@@ -150,75 +155,98 @@ func (socket *Socket) RemoteEnv() *env.Env {
 // Send a command to the remote SDS service.
 // Note that it converts the failure reply into an error. Rather than replying reply itself back to user.
 // In case of successful request, the function returns reply parameters.
+//
+// The request is resent for an infinite amount of time, until the remote service replies.
+// Use RequestRemoteServiceContext() to give up earlier.
 func (socket *Socket) RequestRemoteService(request *message.Request) (map[string]interface{}, error) {
-	request_timeout := REQUEST_TIMEOUT
-	if env.Exists("SDS_REQUEST_TIMEOUT") {
-		env_timeout := env.GetNumeric("SDS_REQUEST_TIMEOUT")
-		if env_timeout != 0 {
-			request_timeout = time.Duration(env_timeout) * time.Second
-		}
-	}
+	return socket.request(context.Background(), request.Command, request.Header, request.ToJSON(), RetryPolicy{})
+}
 
-	// we attempt requests for an infinite amount of time.
-	for {
-		//  We send a request, then we work to get a reply
-		if _, err := socket.socket.SendMessage(request.ToString()); err != nil {
-			return nil, fmt.Errorf("failed to send the command '%s' to '%s'. socket error: %w", request.Command, socket.remoteService.ServiceName(), err)
-		}
+// Send a command to the remote SDS service, the same way as RequestRemoteService().
+//
+// The request is resent according to the socket's retry policy.
+// The function stops as soon as the context is cancelled or its deadline exceeded.
+//
+// The returned errors could be checked against ErrTimeout, ErrRemoteFailure and ErrCancelled
+// using errors.Is(). The failure reply is returned as *RemoteError, use errors.As() to get its code.
+func (socket *Socket) RequestRemoteServiceContext(ctx context.Context, request *message.Request) (map[string]interface{}, error) {
+	return socket.request(ctx, request.Command, request.Header, request.ToJSON(), socket.retry_policy)
+}
 
-		//  Poll socket for a reply, with timeout
-		sockets, err := socket.poller.Poll(request_timeout)
-		if err != nil {
-			return nil, fmt.Errorf("failed to to send the command '%s' to '%s'. poll error: %w", request.Command, socket.remoteService.ServiceName(), err)
-		}
+// Send the command signed by the smartcontract developer to the remote SDS service.
+// Sign the request before, see account.SmartcontractDeveloper.Sign().
+//
+// The signed request is sent only once. If the first request was executed, but its reply was lost,
+// then the same request would be rejected by the nonce check of the SDS Service.
+// Use RequestSigned() to sign the request again with a fresh nonce before resending it.
+func (socket *Socket) RequestSmartcontractDeveloperContext(ctx context.Context, request *message.SmartcontractDeveloperRequest) (map[string]interface{}, error) {
+	if err := socket.validate_request_type(); err != nil {
+		return nil, err
+	}
 
-		//  Here we process a server reply and exit our loop if the
-		//  reply is valid. If we didn't a reply we close the client
-		//  socket and resend the request. We try a number of times
-		//  before finally abandoning:
+	return socket.request(ctx, request.CommandName(), request.RequestHeader(), request.ToJSON(), RetryPolicy{MaxAttempts: 1})
+}
 
-		if len(sockets) > 0 {
-			// Wait for reply.
-			r, err := socket.socket.RecvMessage(0)
-			if err != nil {
-				return nil, fmt.Errorf("failed to receive the command '%s' message from '%s'. socket error: %w", request.Command, socket.remoteService.ServiceName(), err)
-			}
+// Set the retry policy used by the context aware requests.
+func (socket *Socket) SetRetryPolicy(policy RetryPolicy) {
+	socket.retry_policy = policy
+}
 
-			reply, err := message.ParseReply(r)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse the command '%s' reply from '%s'. gosds error %w", request.Command, socket.remoteService.ServiceName(), err)
-			}
+// Set the codec that encodes the requests.
+// The remote service replies with the same codec.
+func (socket *Socket) SetCodec(codec message.Codec) {
+	socket.codec = codec
+}
 
-			if !reply.IsOK() {
-				return nil, fmt.Errorf("the command '%s' replied with a failure by '%s'. the reply error message: %s", request.Command, socket.remoteService.ServiceName(), reply.Message)
-			}
+// Returns the codec that encodes the requests.
+func (socket *Socket) Codec() message.Codec {
+	return socket.codec
+}
 
-			return reply.Params, nil
-		} else {
-			fmt.Println("command '", request.Command, "' wasn't replied by '", socket.remoteService.ServiceName(), "' in ", request_timeout, ", retrying...")
-			err := socket.reconnect()
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
+// Returns the retry policy used by the context aware requests.
+func (socket *Socket) RetryPolicy() RetryPolicy {
+	return socket.retry_policy
 }
 
 // Requests a message to the remote service.
 // The socket parameter is the Request socket from this service.
 // The request is the message.
 func RequestReply[V SDS_Message](socket *Socket, request V) (map[string]interface{}, error) {
+	if err := socket.validate_request_type(); err != nil {
+		return nil, err
+	}
+
+	return socket.request(context.Background(), request.CommandName(), request.RequestHeader(), request.ToJSON(), RetryPolicy{})
+}
+
+// Requests a message to the remote service, the same way as RequestReply().
+//
+// The request is resent according to the socket's retry policy.
+// The function stops as soon as the context is cancelled or its deadline exceeded.
+func RequestReplyContext[V SDS_Message](ctx context.Context, socket *Socket, request V) (map[string]interface{}, error) {
+	if err := socket.validate_request_type(); err != nil {
+		return nil, err
+	}
+
+	return socket.request(ctx, request.CommandName(), request.RequestHeader(), request.ToJSON(), socket.retry_policy)
+}
+
+// Only REQ or DEALER sockets can send the requests.
+func (socket *Socket) validate_request_type() error {
 	socket_type, err := socket.socket.GetType()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if socket_type != zmq.REQ && socket_type != zmq.DEALER {
-		return nil, errors.New("invalid socket type for request-reply. Only REQ or DEALER is supported")
+		return errors.New("invalid socket type for request-reply. Only REQ or DEALER is supported")
 	}
 
-	command_name := request.CommandName()
+	return nil
+}
 
+// The time to wait for the reply of a single attempt.
+func request_timeout() time.Duration {
 	request_timeout := REQUEST_TIMEOUT
 	if env.Exists("SDS_REQUEST_TIMEOUT") {
 		env_timeout := env.GetNumeric("SDS_REQUEST_TIMEOUT")
@@ -227,15 +255,45 @@ func RequestReply[V SDS_Message](socket *Socket, request V) (map[string]interfac
 		}
 	}
 
-	// we attempt requests for an infinite amount of time.
+	return request_timeout
+}
+
+// Encodes the request by the socket's codec, then waits for the reply.
+// If the reply didn't arrive within the request timeout, the socket reconnects
+// and sends the request again, as long as the retry policy allows it.
+//
+// The header is completed by request_header() and sent along with the request.
+// The resent requests keep the same header.
+func (socket *Socket) request(ctx context.Context, command_name string, header message.Header, request map[string]interface{}, policy RetryPolicy) (map[string]interface{}, error) {
+	request_timeout := request_timeout()
+
+	header = request_header(ctx, header)
+	request["header"] = header.ToJSON()
+
+	codec := socket.codec
+	if codec == nil {
+		codec = message.JsonCodec
+	}
+	request_frames, err := message.EncodeFrames(codec, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the command '%s' for '%s': %w", command_name, socket.remoteService.ServiceName(), err)
+	}
+
+	var attempt uint = 0
 	for {
+		attempt++
+
+		// the deadline could expire during the backoff too
+		if err := socket.context_error(ctx, command_name); err != nil {
+			return nil, err
+		}
+
 		//  We send a request, then we work to get a reply
-		if _, err := socket.socket.SendMessage(request.ToString()); err != nil {
+		if _, err := socket.socket.SendMessage(request_frames); err != nil {
 			return nil, fmt.Errorf("failed to send the command '%s' to '%s'. socket error: %w", command_name, socket.remoteService.ServiceName(), err)
 		}
 
-		//  Poll socket for a reply, with timeout
-		sockets, err := socket.poller.Poll(request_timeout)
+		replied, err := socket.wait_reply(ctx, request_timeout)
 		if err != nil {
 			return nil, fmt.Errorf("failed to to send the command '%s' to '%s'. poll error: %w", command_name, socket.remoteService.ServiceName(), err)
 		}
@@ -245,7 +303,7 @@ func RequestReply[V SDS_Message](socket *Socket, request V) (map[string]interfac
 		//  socket and resend the request. We try a number of times
 		//  before finally abandoning:
 
-		if len(sockets) > 0 {
+		if replied {
 			// Wait for reply.
 			r, err := socket.socket.RecvMessage(0)
 			if err != nil {
@@ -256,18 +314,117 @@ func RequestReply[V SDS_Message](socket *Socket, request V) (map[string]interfac
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse the command '%s' reply from '%s'. gosds error %w", command_name, socket.remoteService.ServiceName(), err)
 			}
+			// the older services don't echo the header
+			if reply.Header.RequestId != "" && reply.Header.RequestId != header.RequestId {
+				return nil, fmt.Errorf("the command '%s' request id '%s' mismatch with the reply request id '%s' from '%s'", command_name, header.RequestId, reply.Header.RequestId, socket.remoteService.ServiceName())
+			}
 
 			if !reply.IsOK() {
-				return nil, fmt.Errorf("the command '%s' replied with a failure by '%s'. the reply error message: %s", command_name, socket.remoteService.ServiceName(), reply.Message)
+				return nil, NewRemoteError(socket.remoteService.ServiceName(), command_name, &reply)
 			}
 
 			return reply.Params, nil
+		}
+
+		// The REQ socket is still waiting for the reply,
+		// therefore it can't be used for another request without reconnection.
+		if err := socket.reconnect(); err != nil {
+			return nil, err
+		}
+
+		if err := socket.context_error(ctx, command_name); err != nil {
+			return nil, err
+		}
+
+		if !policy.CanRetry(attempt) {
+			return nil, fmt.Errorf("%w: the command '%s' wasn't replied by '%s' after %d attempts", ErrTimeout, command_name, socket.remoteService.ServiceName(), attempt)
+		}
+
+		policy.notify_retry(attempt, fmt.Errorf("%w: the command '%s' wasn't replied by '%s' in %s", ErrTimeout, command_name, socket.remoteService.ServiceName(), request_timeout))
+
+		backoff := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			backoff.Stop()
+		case <-backoff.C:
+		}
+	}
+}
+
+// Returns ErrTimeout if the context deadline exceeded, ErrCancelled if the context was cancelled.
+// Returns nil if the context is still active.
+func (socket *Socket) context_error(ctx context.Context, command_name string) error {
+	ctx_err := ctx.Err()
+	if ctx_err == nil {
+		return nil
+	}
+	if errors.Is(ctx_err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: the command '%s' wasn't replied by '%s' before the deadline", ErrTimeout, command_name, socket.remoteService.ServiceName())
+	}
+	return fmt.Errorf("%w: the command '%s' to '%s': %s", ErrCancelled, command_name, socket.remoteService.ServiceName(), ctx_err.Error())
+}
+
+// Sets the missing fields of the request header:
+//   - the random request id and the creation time.
+//   - the deadline of the context, if it's earlier than the header's deadline.
+//   - the traceparent. If the context carries the header of the incoming request,
+//     then the trace is continued, otherwise a new trace is started.
+func request_header(ctx context.Context, header message.Header) message.Header {
+	if header.RequestId == "" {
+		header.RequestId = message.NewRequestId()
+	}
+	if header.CreatedAt.IsZero() {
+		header.CreatedAt = time.Now()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if !header.HasDeadline() || deadline.Before(header.Deadline) {
+			header.Deadline = deadline
+		}
+	}
+	if header.Traceparent == "" {
+		parent, ok := message.HeaderFromContext(ctx)
+		if ok && parent.Traceparent != "" {
+			header.Traceparent = message.ChildTraceparent(parent.Traceparent)
 		} else {
-			fmt.Println("command '", command_name, "' wasn't replied by '", socket.remoteService.ServiceName(), "' in ", request_timeout, ", retrying...")
-			err := socket.reconnect()
-			if err != nil {
-				return nil, err
-			}
+			header.Traceparent = message.NewTraceparent()
+		}
+	}
+
+	return header
+}
+
+// Polls the socket until the reply arrives, the request timeout passes or the context is done.
+// Returns true if the reply is ready to be read.
+func (socket *Socket) wait_reply(ctx context.Context, request_timeout time.Duration) (bool, error) {
+	// without a cancellation there is no need to wake up
+	if ctx.Done() == nil {
+		sockets, err := socket.poller.Poll(request_timeout)
+		if err != nil {
+			return false, err
+		}
+		return len(sockets) > 0, nil
+	}
+
+	timeout := time.Now().Add(request_timeout)
+	for {
+		left := time.Until(timeout)
+		if left <= 0 {
+			return false, nil
+		}
+		if left > poll_interval {
+			left = poll_interval
+		}
+
+		sockets, err := socket.poller.Poll(left)
+		if err != nil {
+			return false, err
+		}
+		if len(sockets) > 0 {
+			return true, nil
+		}
+
+		if ctx.Err() != nil {
+			return false, nil
 		}
 	}
 }
@@ -279,21 +436,34 @@ func TcpRequestSocketOrPanic(e *env.Env, client *env.Env) *Socket {
 		panic(fmt.Errorf("missing .env variable: Please set '" + e.ServiceName() + "' host and port and curve key if security was enabled"))
 	}
 
-	sock, err := zmq.NewSocket(zmq.REQ)
+	socket, err := new_tcp_request_socket(e, client)
 	if err != nil {
 		panic(err)
 	}
+
+	return socket
+}
+
+// Create a new Socket on TCP protocol, connected to the remote service.
+// The socket is the wrapper over zmq.REQ
+func new_tcp_request_socket(e *env.Env, client *env.Env) (*Socket, error) {
+	sock, err := zmq.NewSocket(zmq.REQ)
+	if err != nil {
+		return nil, err
+	}
 	new_socket := Socket{
 		remoteService: e,
 		thisService:   client,
 		socket:        sock,
+		retry_policy:  DefaultRetryPolicy(),
+		codec:         message.JsonCodec,
 	}
 	err = new_socket.reconnect()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return &new_socket
+	return &new_socket, nil
 }
 
 // Create a new Socket on TCP protocol otherwise exit from the program
@@ -326,5 +496,7 @@ func TcpSubscriberOrPanic(e *env.Env, client_env *env.Env) *Socket {
 	return &Socket{
 		remoteService: e,
 		socket:        socket,
+		retry_policy:  DefaultRetryPolicy(),
+		codec:         message.JsonCodec,
 	}
 }
@@ -1,8 +1,10 @@
 package remote
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/blocklords/gosds/message"
@@ -10,6 +12,17 @@ import (
 	zmq "github.com/pebbe/zmq4"
 )
 
+// Subscribe to the heartbeats of the Broadcaster.
+// See Subscribe() for the use of it.
+func (socket *Socket) SetHeartbeatFilter() error {
+	return socket.SetSubscribeFilter(message.HEARTBEAT_TOPIC)
+}
+
+// The time after which Subscribe() considers the Broadcaster as gone if no heartbeat arrived.
+func HeartbeatTimeout() time.Duration {
+	return message.HEARTBEAT_INTERVAL * message.HEARTBEAT_LIVENESS
+}
+
 // The Socket if its a Subscriber applies a filter to listen certain data from the Broadcaster.
 func (socket *Socket) SetSubscribeFilter(topic string) error {
 	socketType, err := socket.socket.GetType()
@@ -23,52 +36,310 @@ func (socket *Socket) SetSubscribeFilter(topic string) error {
 	return socket.socket.SetSubscribe(topic)
 }
 
+// The message of the failure reply that Subscribe() sends if some broadcasts were dropped.
+// The reply has the message.GAP code, use IsGap() to check it and ParseGap() to get the missing range.
+const GAP = "gap"
+
 // Subscribe to the SDS Broadcast.
 // The function is intended to be called as a gouritine.
 //
 // When a new message arrives, the method will send it to the channel.
+// The function returns once anything is sent to the exit channel,
+// or if the subscription failed, for example the polling error.
+// Therefore the exit signal should not block after the function returned:
+//
+//	done := make(chan struct{})
+//	go func() {
+//		socket.Subscribe(channel, exit_channel, time_out)
+//		close(done)
+//	}()
+//	...
+//	select {
+//	case exit_channel <- 1:
+//	case <-done:
+//	}
+//	<-done
+//	socket.Close()
 //
-// if time is out, it will send the timeout message.
+// The socket is not thread safe, close it only after the function returned.
+//
+// See SubscribeContext() for the timeout and the failures sent to the channel.
 func (socket *Socket) Subscribe(channel chan message.Reply, exit_channel chan int, time_out time.Duration) {
+	socket.SubscribeWithFilters(channel, exit_channel, nil, time_out)
+}
+
+// The request to subscribe to more topics during the subscription.
+// The socket is not thread safe, therefore the filters are set by the subscription itself.
+//
+// The Result receives nil once the filters are set, or the error.
+// It should be buffered, the subscription doesn't wait for the reader.
+type FilterRequest struct {
+	Topics []string
+	Result chan error
+}
+
+// Subscribe to the SDS Broadcast, the same way as Subscribe().
+// The topics sent over the filters channel are subscribed by the running subscription, see FilterRequest.
+func (socket *Socket) SubscribeWithFilters(channel chan message.Reply, exit_channel chan int, filters <-chan FilterRequest, time_out time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-exit_channel:
+			fmt.Println("exit signal was received for subscriber")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	socket.subscribe(ctx, channel, filters, time_out)
+}
+
+// Subscribe to the SDS Broadcast until the context is done.
+// The function is intended to be called as a gouritine.
+//
+// When a new message arrives, the method will send it to the channel immediately.
+//
+// if time is out, it will send the failure with message.TIMEOUT code.
+// Any message including the heartbeat resets the timer. Therefore, if the
+// subscriber is subscribed to message.HEARTBEAT_TOPIC, the timeout means that the broadcaster is gone,
+// not that it's idle. The heartbeats are not sent to the channel.
+//
+// If the sequence of the topic jumped, then before the message it sends the
+// failure with the message.GAP code. The gap parameters are the topic and the missing sequence range.
+// If the sequence goes back, then the broadcaster was restarted and the tracking starts over.
+func (socket *Socket) SubscribeContext(ctx context.Context, channel chan message.Reply, time_out time.Duration) {
+	socket.subscribe(ctx, channel, nil, time_out)
+}
+
+// Subscribe until the context is done, see SubscribeContext().
+// The filters channel is optional, see FilterRequest.
+func (socket *Socket) subscribe(ctx context.Context, channel chan message.Reply, filters <-chan FilterRequest, time_out time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// returns false if the subscription was stopped
+	send := func(reply message.Reply) bool {
+		select {
+		case channel <- reply:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	socketType, err := socket.socket.GetType()
 	if err != nil {
-		channel <- message.Fail("failed to check the socket type. the socket error: " + err.Error())
+		send(message.Fail("failed to check the socket type. the socket error: " + err.Error()))
 		return
 	}
 	if socketType != zmq.SUB {
-		channel <- message.Fail("the socket is not a Broadcast. Can not call subscribe")
+		send(message.Fail("the socket is not a Broadcast. Can not call subscribe"))
+		return
+	}
+
+	// The poller can't wait for the context and the filters.
+	// Therefore they are passed over the inproc socket.
+	control, queued_filters, err := new_control_socket(ctx, filters)
+	if err != nil {
+		send(message.Fail("failed to create the subscriber control socket: " + err.Error()))
 		return
 	}
+	defer control.Close()
+
+	poller := zmq.NewPoller()
+	poller.Add(socket.socket, zmq.POLLIN)
+	poller.Add(control, zmq.POLLIN)
 
-	timer := time.AfterFunc(time_out, func() {
-		channel <- message.Fail("timeout")
-	})
-	defer timer.Stop()
+	deadline := time.Now().Add(time_out)
+	timed_out := false
+
+	// the last received sequence of each topic
+	sequences := map[string]uint64{}
 
 	for {
-		select {
-		case <-exit_channel:
-			if !timer.Stop() {
-				<-timer.C
-			}
-			fmt.Println("exit signal was received for subscriber")
+		wait := time.Until(deadline)
+		if timed_out {
+			// wait for the messages, without notifying the timeout again
+			wait = -1
+		} else if wait < 0 {
+			wait = 0
+		}
+
+		polled, err := poller.Poll(wait)
+		if err != nil {
+			send(message.Fail("failed to poll the subscriber: " + err.Error()))
 			return
-		default:
-			msgRaw, err := socket.socket.RecvMessage(zmq.DONTWAIT)
+		}
+
+		if len(polled) == 0 {
+			timed_out = true
+			if !send(message.FailWithCode(message.TIMEOUT, "timeout")) {
+				return
+			}
+			continue
+		}
 
+		for _, item := range polled {
+			if item.Socket == control {
+				signal, err := control.Recv(0)
+				if err != nil || signal != control_filter {
+					return
+				}
+				request := <-queued_filters
+				request.Result <- socket.set_subscribe_filters(request.Topics)
+				continue
+			}
+
+			msgRaw, err := socket.socket.RecvMessage(0)
 			if err != nil {
-				time.Sleep(time.Millisecond * 200)
+				if !send(message.Fail("Error when receiving message: " + err.Error())) {
+					return
+				}
 				continue
 			}
-			timer.Reset(time_out)
+			deadline = time.Now().Add(time_out)
+			timed_out = false
 
 			broadcast, err := message.ParseBroadcast(msgRaw)
 			if err != nil {
-				channel <- message.Fail("Error when parsing message: " + err.Error())
+				if !send(message.FailWithCode(message.INVALID_PARAMS, "Error when parsing message: "+err.Error())) {
+					return
+				}
+				continue
+			}
+
+			if broadcast.IsHeartbeat() {
 				continue
 			}
 
-			channel <- broadcast.Reply()
+			if broadcast.Sequence > 0 {
+				last, tracked := sequences[broadcast.Topic]
+				if tracked && broadcast.Sequence > last+1 {
+					if !send(NewGap(broadcast.Topic, last+1, broadcast.Sequence-1)) {
+						return
+					}
+				}
+				sequences[broadcast.Topic] = broadcast.Sequence
+			}
+
+			if !send(broadcast.Reply()) {
+				return
+			}
 		}
 	}
 }
+
+// Used to make unique inproc endpoints
+var control_counter uint64
+
+// The signals of the control socket
+const (
+	control_exit   = "exit"
+	control_filter = "filter"
+)
+
+// Creates the socket that receives the control_exit signal when the context is done,
+// and the control_filter signal when the filter request is queued.
+// The queued request should be taken after its signal.
+func new_control_socket(ctx context.Context, filters <-chan FilterRequest) (*zmq.Socket, chan FilterRequest, error) {
+	url := fmt.Sprintf("inproc://subscriber_control_%d", atomic.AddUint64(&control_counter, 1))
+
+	receiver, err := zmq.NewSocket(zmq.PAIR)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := receiver.Bind(url); err != nil {
+		receiver.Close()
+		return nil, nil, err
+	}
+
+	sender, err := zmq.NewSocket(zmq.PAIR)
+	if err != nil {
+		receiver.Close()
+		return nil, nil, err
+	}
+	if err := sender.Connect(url); err != nil {
+		sender.Close()
+		receiver.Close()
+		return nil, nil, err
+	}
+
+	queued := make(chan FilterRequest, 1)
+
+	// the sender is used only by this goroutine
+	go func() {
+		defer func() {
+			sender.SendMessageDontwait(control_exit)
+			sender.SetLinger(0)
+			sender.Close()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case request := <-filters:
+				select {
+				case queued <- request:
+				case <-ctx.Done():
+					return
+				}
+				if _, err := sender.Send(control_filter, 0); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return receiver, queued, nil
+}
+
+// Subscribes to the topics
+func (socket *Socket) set_subscribe_filters(topics []string) error {
+	for _, topic := range topics {
+		if err := socket.socket.SetSubscribe(topic); err != nil {
+			return errors.New("failed to subscribe to '" + topic + "': " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Creates the failure reply about the missing broadcasts of the topic.
+// The from and to are the first and last missing sequence numbers.
+func NewGap(topic string, from uint64, to uint64) message.Reply {
+	reply := message.FailWithCode(message.GAP, GAP)
+	reply.Params = map[string]interface{}{
+		"topic":         topic,
+		"sequence_from": from,
+		"sequence_to":   to,
+	}
+	return reply
+}
+
+// Whether the reply sent by Subscribe() is about the missing broadcasts
+func IsGap(reply *message.Reply) bool {
+	return !reply.IsOK() && reply.Code == message.GAP
+}
+
+// Returns the topic, the first and the last missing sequence numbers of the gap reply.
+func ParseGap(reply *message.Reply) (string, uint64, uint64, error) {
+	if !IsGap(reply) {
+		return "", 0, 0, errors.New("the reply is not a gap")
+	}
+	topic, err := message.GetString(reply.Params, "topic")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	from, err := message.GetUint64(reply.Params, "sequence_from")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	to, err := message.GetUint64(reply.Params, "sequence_to")
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	return topic, from, to, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/blocklords/gosds/env"
 	"github.com/blocklords/gosds/static"
@@ -46,9 +47,40 @@ func (kvm *KVM) Close() {
 // Return it.
 func (kvm *KVM) TopicFilter() *topic.TopicFilter { return kvm.topicFilter }
 
+// The prefix of the keys of the topic filter.
+//
+// It's the topic filter string as it was before topic.TopicFilter.ToString() became canonical:
+// the values are in the order they were set, and every path ends with `;`.
+// Changing it would orphan the block timestamps cached by the earlier versions.
+// The versions weren't part of the filter, so their path is added only when it's set.
+func (kvm *KVM) filter_prefix() string {
+	paths := []struct {
+		name   string
+		values []string
+	}{
+		{"o", kvm.topicFilter.Organizations},
+		{"p", kvm.topicFilter.Projects},
+		{"n", kvm.topicFilter.NetworkIds},
+		{"g", kvm.topicFilter.Groups},
+		{"s", kvm.topicFilter.Smartcontracts},
+		{"v", kvm.topicFilter.Versions},
+		{"m", kvm.topicFilter.Methods},
+		{"e", kvm.topicFilter.Events},
+	}
+
+	str := ""
+	for _, path := range paths {
+		if len(path.values) > 0 {
+			str += path.name + ":" + strings.Join(path.values, ",") + ";"
+		}
+	}
+
+	return str
+}
+
 // Block Timestamp of the smartcontract on the client side.
 func (kvm *KVM) KeyBlockTimestamp(key static.SmartcontractKey) []byte {
-	topicString := kvm.topicFilter.ToString()
+	topicString := kvm.filter_prefix()
 	keyString := string(key)
 
 	return []byte(fmt.Sprintf("%s_%s_subcriber_block_timestamp", topicString, keyString))
@@ -56,7 +88,7 @@ func (kvm *KVM) KeyBlockTimestamp(key static.SmartcontractKey) []byte {
 
 // Topic string of the smartcontract on the client side.
 func (kvm *KVM) KeyTopicString(key static.SmartcontractKey) []byte {
-	topicString := kvm.topicFilter.ToString()
+	topicString := kvm.filter_prefix()
 	keyString := string(key)
 
 	return []byte(fmt.Sprintf("%s_%s_subcriber_topic_string", topicString, keyString))
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/blocklords/gosds/static"
+	"github.com/blocklords/gosds/topic"
+)
+
+// The keys must stay the same as the earlier versions wrote them,
+// otherwise the subscriber would lose the cached block timestamps.
+func TestKeysAreStable(t *testing.T) {
+	key := static.CreateSmartcontractKey("1", "0xdead")
+
+	vectors := []struct {
+		filter   topic.TopicFilter
+		expected string
+	}{
+		{
+			topic.TopicFilter{},
+			"_1.0xdead",
+		},
+		{
+			topic.NewFilterTopic([]string{"seascape"}, []string{"lords", "blocklords", "lords"}, nil, []string{"nft"}, nil, nil, []string{"Transfer"}),
+			"o:seascape;p:lords,blocklords,lords;g:nft;e:Transfer;_1.0xdead",
+		},
+		{
+			topic.NewFilterTopic([]string{"seascape"}, []string{"blocklords"}, []string{"1", "56"}, []string{"nft"}, []string{"Hero"}, []string{"mint"}, nil),
+			"o:seascape;p:blocklords;n:1,56;g:nft;s:Hero;m:mint;_1.0xdead",
+		},
+		{
+			topic.TopicFilter{Organizations: []string{"seascape"}, Versions: []string{"v2"}},
+			"o:seascape;v:v2;_1.0xdead",
+		},
+	}
+
+	for _, vector := range vectors {
+		kvm := &KVM{topicFilter: &vector.filter}
+
+		if got := string(kvm.KeyBlockTimestamp(key)); got != vector.expected+"_subcriber_block_timestamp" {
+			t.Errorf("block timestamp key %s", got)
+		}
+		if got := string(kvm.KeyTopicString(key)); got != vector.expected+"_subcriber_topic_string" {
+			t.Errorf("topic string key %s", got)
+		}
+	}
+}
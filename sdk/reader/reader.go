@@ -1,38 +1,84 @@
 package reader
 
 import (
+	"context"
+	"errors"
+
+	"github.com/blocklords/gosds/account"
 	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
+	"github.com/blocklords/gosds/static"
 	"github.com/blocklords/gosds/topic"
 )
 
 type Reader struct {
-	socket  *remote.Socket // SDS Gateway
-	address string         // Account address granted for reading
+	socket  remote.Requester                // SDS Gateway
+	address string                          // Account address granted for reading
+	signer  *account.SmartcontractDeveloper // optional, signs the requests
 }
 
-func NewReader(gatewaySocket *remote.Socket, address string) *Reader {
+func NewReader(gatewaySocket remote.Requester, address string) *Reader {
 	return &Reader{socket: gatewaySocket, address: address}
 }
 
+// Sign all requests by the smartcontract developer with the private key.
+// The requests are sent as message.SmartcontractDeveloperRequest,
+// so the SDS Gateway authenticates the reader.
+func (r *Reader) SetSigner(signer *account.SmartcontractDeveloper) {
+	r.signer = signer
+}
+
 func (r *Reader) Read(t topic.Topic, args map[string]interface{}) message.Reply {
 	if t.Level() != topic.FULL_LEVEL {
 		return message.Fail(`Topic should contain method name`)
 	}
 
-	request := message.Request{
-		Command: "smartcontract_read",
-		Parameters: map[string]interface{}{
-			"topic_string": t.ToString(topic.FULL_LEVEL),
-			"arguments":    args,
-			"address":      r.address,
-		},
+	parameters := map[string]interface{}{
+		"topic_string": t.ToString(topic.FULL_LEVEL),
+		"arguments":    args,
+		"address":      r.address,
 	}
 
-	params, err := r.socket.RequestRemoteService(&request)
+	var params map[string]interface{}
+	var err error
+	if r.signer == nil {
+		request := message.Request{
+			Command:    "smartcontract_read",
+			Parameters: parameters,
+		}
+		params, err = r.socket.RequestRemoteService(&request)
+	} else {
+		request := message.SmartcontractDeveloperRequest{
+			Command:    "smartcontract_read",
+			Parameters: parameters,
+		}
+		// the read could be repeated, the resent request is signed again
+		params, err = remote.RequestSigned(context.Background(), r.socket, r.signer, &request, remote.DefaultRetryPolicy())
+	}
 	if err != nil {
-		return message.Fail(err.Error())
+		return remote.ErrorReply(err)
 	}
 
 	return message.Reply{Status: "OK", Message: "", Params: params}
 }
+
+// Returns the groups of the partial topic, for example the topic with the organization and project only.
+//
+// The topic should have at least the organization.
+func (r *Reader) Groups(t topic.Topic) ([]string, error) {
+	if len(t.Organization) == 0 {
+		return nil, errors.New("the topic should have the organization")
+	}
+	return static.RemoteGroups(r.socket, &t)
+}
+
+// Returns the smartcontracts of the partial topic with their methods and events,
+// so the backend developer could find the topics without asking the smartcontract developer.
+//
+// The topic should have at least the organization. The method and event paths are ignored.
+func (r *Reader) Discover(t topic.Topic) ([]*static.SmartcontractInterface, error) {
+	if len(t.Organization) == 0 {
+		return nil, errors.New("the topic should have the organization")
+	}
+	return static.RemoteSmartcontractInterfaces(r.socket, &t)
+}
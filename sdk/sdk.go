@@ -59,13 +59,42 @@ example of using Subscribe
 
 			// catch channel data
 	   }
+
+-------------------------------------------
+
+example of routing the subscribed data to several consumers
+
+	   func(test) {
+			router := topic.NewRouter()
+			transfers, _ := topic.ParseFilterString("s:Scape*;e:Transfer*")
+			router.Handle(transfers, func(t topic.Topic, data interface{}) {
+				log := data.(*categorizer.Log)
+			})
+			stakes, _ := topic.ParseFilterString("g:staking;m:stake,unstake")
+			router.Handle(stakes, func(t topic.Topic, data interface{}) {
+				transaction := data.(*categorizer.Transaction)
+			})
+
+			err := subscriber.Start()
+			if err := nil {
+				panic(err)
+			}
+
+			// blocks until the subscriber stops, err is the reason
+			err = subscriber.Route(router)
+	   }
 */
 package sdk
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"errors"
+	"strings"
 
+	"github.com/blocklords/gosds/account"
 	"github.com/blocklords/gosds/env"
+	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
 	"github.com/blocklords/gosds/sdk/db"
 	"github.com/blocklords/gosds/sdk/reader"
@@ -76,13 +105,86 @@ import (
 
 var Version string = "Seascape GoSDS version: 0.0.8"
 
+// The optional settings of the Reader and Writer
+type Option func(*options)
+
+type options struct {
+	signer           *account.SmartcontractDeveloper
+	signature_scheme string
+}
+
+// Sign the requests by the ECDSA private key of the smartcontract developer.
+// The SDS Gateway then authenticates the requests end to end.
+//
+// The address given to NewReader() or NewWriter() should be the address of the private key,
+// or empty to use the address of the private key.
+//
+// Every signed request has its own nonce, the unix timestamp in seconds, and the SDS Service accepts
+// only the growing nonces. Therefore the signer sends one request per second on average.
+// The requests are sent one by one, and after a burst of account.MAX_NONCE_LEAD
+// requests the signer waits for the clock.
+func WithSigner(private_key *ecdsa.PrivateKey) Option {
+	return func(o *options) {
+		o.signer = account.NewEcdsaPrivateKey(private_key)
+	}
+}
+
+// Sign the requests by the ed25519 private key of the smartcontract developer.
+// The address is the hex of the public key.
+// The requests are limited the same way as by WithSigner().
+func WithEd25519Signer(private_key ed25519.PrivateKey) Option {
+	return func(o *options) {
+		o.signer = account.NewEd25519PrivateKey(private_key)
+	}
+}
+
+// Sign the requests by EIP-712 typed data instead of personal_sign.
+// The wallets show the command and the parameters of the typed data to the user.
+// The domain is message.GetEip712Domain(), it should match the domain of the SDS Gateway.
+//
+// Used along with WithSigner(), the ed25519 keys always sign by message.ED25519_SCHEME.
+func WithEip712() Option {
+	return func(o *options) {
+		o.signature_scheme = message.EIP712_SCHEME
+	}
+}
+
+// Applies the options, and returns the address of the reader or writer
+func apply_options(address string, list []Option) (string, *options, error) {
+	o := &options{}
+	for _, option := range list {
+		option(o)
+	}
+
+	if o.signer != nil {
+		if o.signature_scheme != "" {
+			o.signer.SignatureScheme = o.signature_scheme
+		}
+		if address == "" {
+			address = o.signer.Address
+		} else if !strings.EqualFold(address, o.signer.Address) {
+			return "", nil, errors.New("the address " + address + " mismatches the signer address " + o.signer.Address)
+		}
+	}
+
+	return address, o, nil
+}
+
 // Returns a new reader.Reader.
+// The reader is safe for concurrent use.
 //
 // The repUrl is the link to the SDS Gateway.
 // The address argument is the wallet address that is allowed to read.
 //
 //	address is the whitelisted user's address.
-func NewReader(address string) (*reader.Reader, error) {
+//
+// Pass WithSigner() to sign the requests.
+func NewReader(address string, opts ...Option) (*reader.Reader, error) {
+	address, o, err := apply_options(address, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := gatewayEnv(false)
 	if err != nil {
 		return nil, err
@@ -93,12 +195,29 @@ func NewReader(address string) (*reader.Reader, error) {
 		return nil, err
 	}
 
-	gatewaySocket := remote.TcpRequestSocketOrPanic(e, developer_env)
+	gatewayPool, err := remote.NewTcpRequestPool(e, developer_env, requestPoolSize())
+	if err != nil {
+		return nil, err
+	}
+
+	r := reader.NewReader(gatewayPool, address)
+	if o.signer != nil {
+		r.SetSigner(o.signer)
+	}
 
-	return reader.NewReader(gatewaySocket, address), nil
+	return r, nil
 }
 
-func NewWriter(address string) (*writer.Writer, error) {
+// Returns a new writer.Writer.
+// The writer is safe for concurrent use.
+//
+// Pass WithSigner() to sign the requests.
+func NewWriter(address string, opts ...Option) (*writer.Writer, error) {
+	address, o, err := apply_options(address, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := gatewayEnv(false)
 	if err != nil {
 		return nil, err
@@ -109,9 +228,17 @@ func NewWriter(address string) (*writer.Writer, error) {
 		return nil, err
 	}
 
-	gatewaySocket := remote.TcpRequestSocketOrPanic(e, developer_env)
+	gatewayPool, err := remote.NewTcpRequestPool(e, developer_env, requestPoolSize())
+	if err != nil {
+		return nil, err
+	}
+
+	w := writer.NewWriter(gatewayPool, address)
+	if o.signer != nil {
+		w.SetSigner(o.signer)
+	}
 
-	return writer.NewWriter(gatewaySocket, address), nil
+	return w, nil
 }
 
 // Returns a new subscriber
@@ -154,6 +281,17 @@ func gatewayEnv(broadcast bool) (*env.Env, error) {
 	return e, nil
 }
 
+// Amount of the parallel requests that Reader or Writer can send to the SDS Gateway.
+// Set by the 'SDS_REQUEST_POOL_SIZE' environment variable.
+func requestPoolSize() int {
+	size := env.GetNumeric("SDS_REQUEST_POOL_SIZE")
+	if size == 0 {
+		return remote.DEFAULT_POOL_SIZE
+	}
+
+	return int(size)
+}
+
 func developer_env() (*env.Env, error) {
 	e, err := env.Developer()
 	if err != nil {
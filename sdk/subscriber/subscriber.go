@@ -2,8 +2,12 @@
 package subscriber
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blocklords/gosds/categorizer"
@@ -12,6 +16,7 @@ import (
 	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
 	"github.com/blocklords/gosds/static"
+	"github.com/blocklords/gosds/topic"
 
 	"github.com/blocklords/gosds/sdk/db"
 )
@@ -24,6 +29,9 @@ type Subscriber struct {
 
 	BroadcastChan   chan message.Broadcast
 	broadcastSocket *remote.Socket
+	subscribed      chan struct{}             // closed when the broadcastSocket.Subscribe() returns
+	filters         chan remote.FilterRequest // the new smartcontracts to subscribe by the running subscription
+	follow_interval time.Duration             // 0 if the subscriber doesn't follow the redeployments
 }
 
 // Create a new subscriber for a given user and his topic filter.
@@ -35,7 +43,11 @@ func NewSubscriber(gatewaySocket *remote.Socket, db *db.KVM, address string, cle
 		smartcontractKeys: make([]*static.SmartcontractKey, 0),
 	}
 
-	err := subscriber.load_smartcontracts(clear_cache)
+	smartcontracts, topicStrings, err := static.RemoteSmartcontracts(gatewaySocket, db.TopicFilter())
+	if err != nil {
+		return nil, err
+	}
+	_, err = subscriber.load_smartcontracts(smartcontracts, topicStrings, clear_cache)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +55,22 @@ func NewSubscriber(gatewaySocket *remote.Socket, db *db.KVM, address string, cle
 	return &subscriber, nil
 }
 
+// Follow the topics across the redeployments and the upgrades of the smartcontracts.
+//
+// Every interval the subscriber requests the smartcontracts of the topic filter again.
+// The new smartcontracts, for example the redeployed ones, are subscribed,
+// and their data since the deployment is sent as the snapshot.
+// The previous addresses of the topic stay subscribed.
+//
+// The configuration history of every topic is not requested, since
+// the smartcontracts of the topic filter already include the redeployed ones,
+// and a single request covers all topics of the filter.
+//
+// Call it before Start().
+func (s *Subscriber) Follow(interval time.Duration) {
+	s.follow_interval = interval
+}
+
 // Connect the client to the SDS Publisher broadcast.
 // Then start to queue the incoming data from the broadcaster.
 // The queued messages will be read and cached by the Subscriber.read_from_publisher() after getting the snapshot.
@@ -59,6 +87,12 @@ func (subscriber *Subscriber) connect_to_publisher() error {
 	// Run the Subscriber that is connected to the Broadcaster
 	subscriber.broadcastSocket = remote.TcpSubscriberOrPanic(gateway_env, developer_env)
 
+	// The heartbeats tell that the publisher is alive, even if the smartcontracts are idle.
+	if err := subscriber.broadcastSocket.SetHeartbeatFilter(); err != nil {
+		subscriber.broadcastSocket.Close()
+		return fmt.Errorf("failed to subscribe to the heartbeat: " + err.Error())
+	}
+
 	// Subscribing to the events, but we will not call the sub.ReceiveMessage
 	// until we will not get the snapshot of the missing data.
 	// ZMQ will queue the data until we will not call sub.ReceiveMessage.
@@ -76,6 +110,7 @@ func (subscriber *Subscriber) connect_to_publisher() error {
 // The Start() method creates a channel for sending the data to the client.
 // Then it connects to the SDS Gateway to get the snapshots.
 // Finally, it will receive the messages from SDS Publisher.
+// The BroadcastChan is closed when the subscriber stops after a failure.
 func (s *Subscriber) Start() error {
 	fmt.Println("Starting the subscription!")
 
@@ -92,10 +127,10 @@ func (s *Subscriber) Start() error {
 	return nil
 }
 
-// Returns the latest updated block timestamp in the cache
-func (s *Subscriber) recent_block_timestamp() uint64 {
+// Returns the latest updated block timestamp in the cache among the given smartcontracts
+func (s *Subscriber) recent_block_timestamp(keys []*static.SmartcontractKey) uint64 {
 	var recent_block_timestamp uint64 = 0
-	for _, key := range s.smartcontractKeys {
+	for _, key := range keys {
 		block_timestamp := s.db.GetBlockTimestamp(*key)
 		fmt.Println("recent block timestamp: ", *key, block_timestamp)
 		if block_timestamp > recent_block_timestamp {
@@ -108,17 +143,29 @@ func (s *Subscriber) recent_block_timestamp() uint64 {
 
 // Get the snapshot since the latest cached till the most recent updated time.
 func (s *Subscriber) get_snapshot() error {
+	return s.get_snapshot_of(s.smartcontractKeys)
+}
+
+// Get the snapshot of the given smartcontracts since the latest cached till the most recent updated time.
+func (s *Subscriber) get_snapshot_of(keys []*static.SmartcontractKey) error {
+	// if block_timestamp_to is 0, then get snapshot till the most recent block update.
+	return s.get_snapshot_range(keys, s.recent_block_timestamp(keys), 0, nil)
+}
+
+// Get the snapshot of the given smartcontracts between the block timestamps.
+// If block_timestamp_to is 0, then till the most recent updated time.
+//
+// If the sent map is given, then the ids of the sent transactions and logs are added to it,
+// see transaction_id() and log_id().
+func (s *Subscriber) get_snapshot_range(keys []*static.SmartcontractKey, block_timestamp_from uint64, block_timestamp_to uint64, sent map[string]bool) error {
 	limit := uint64(500)
 	page := uint64(1)
-	block_timestamp_from := s.recent_block_timestamp()
-	// if block_timestamp_to is 0, then get snapshot till the most recent block update.
-	block_timestamp_to := uint64(0)
 
 	for {
 		request := message.Request{
 			Command: "snapshot_get",
 			Parameters: map[string]interface{}{
-				"smartcontract_keys":   generic_type.ToStringList(s.smartcontractKeys),
+				"smartcontract_keys":   generic_type.ToStringList(keys),
 				"block_timestamp_from": block_timestamp_from,
 				"block_timestamp_to":   block_timestamp_to,
 				"page":                 page,
@@ -179,6 +226,15 @@ func (s *Subscriber) get_snapshot() error {
 			logs[i] = log
 		}
 
+		if sent != nil {
+			for _, tx := range transactions {
+				sent[transaction_id(tx)] = true
+			}
+			for _, log := range logs {
+				sent[log_id(log)] = true
+			}
+		}
+
 		reply := message.Reply{
 			Status:  "OK",
 			Message: "",
@@ -198,8 +254,21 @@ func (s *Subscriber) get_snapshot() error {
 	}
 }
 
-// calls the snapshot then incoming data in real-time from SDS Publisher
+// The id of the transaction to find the duplicates
+func transaction_id(tx *categorizer.Transaction) string {
+	return tx.NetworkId + "/" + tx.Txid
+}
+
+// The id of the log to find the duplicates
+func log_id(log *categorizer.Log) string {
+	return log.NetworkId + "/" + log.Txid + "/" + strconv.FormatUint(uint64(log.LogIndex), 10)
+}
+
+// calls the snapshot then incoming data in real-time from SDS Publisher.
+// The BroadcastChan is closed when the subscriber stops.
 func (s *Subscriber) get_data() {
+	defer close(s.BroadcastChan)
+
 	err := s.get_snapshot()
 	if err != nil {
 		s.BroadcastChan <- message.NewBroadcast("error", message.Fail(err.Error()))
@@ -212,25 +281,28 @@ func (s *Subscriber) get_data() {
 	}
 }
 
-// Get the list of the smartcontracts by smartcontract filter from SDS Categorizer via SDS Gateway
-// Then cache them out and list in the Subscriber data structure
-func (s *Subscriber) load_smartcontracts(clear_cache bool) error {
-	// preparing the subscriber so that we catch the first message if it was send
-	// by publisher.
-
-	smartcontracts, topicStrings, err := static.RemoteSmartcontracts(s.socket, s.db.TopicFilter())
-	if err != nil {
-		return err
+// Cache the smartcontracts of the topic filter, returned by SDS Categorizer via SDS Gateway,
+// and list them in the Subscriber data structure.
+//
+// Returns the smartcontracts that were not tracked before.
+func (s *Subscriber) load_smartcontracts(smartcontracts []*static.Smartcontract, topicStrings []string, clear_cache bool) ([]*static.SmartcontractKey, error) {
+	tracked := make(map[static.SmartcontractKey]bool, len(s.smartcontractKeys))
+	for _, key := range s.smartcontractKeys {
+		tracked[*key] = true
 	}
+	new_keys := make([]*static.SmartcontractKey, 0)
 
 	// set the smartcontract keys
 	for i, sm := range smartcontracts {
 		key := sm.KeyString()
+		if tracked[key] {
+			continue
+		}
 
 		if clear_cache {
 			err := s.db.DeleteBlockTimestamp(key)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 		// cache the smartcontract block timestamp
@@ -241,38 +313,131 @@ func (s *Subscriber) load_smartcontracts(clear_cache bool) error {
 			blockTimestamp = uint64(sm.PreDeployBlockTimestamp)
 			err := s.db.SetBlockTimestamp(key, blockTimestamp)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
 		// cache the topic string
 		topicString := topicStrings[i]
-		err = s.db.SetTopicString(key, topicString)
+		err := s.db.SetTopicString(key, topicString)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// finally track the smartcontract
 		s.smartcontractKeys = append(s.smartcontractKeys, &key)
+		new_keys = append(new_keys, &key)
 	}
 
-	return nil
+	return new_keys, nil
+}
+
+// Returns the new smartcontracts of the topic filter, see Follow().
+//
+// It's called by the subscription loop, therefore the request gives up after the follow interval,
+// or earlier if the retry policy of the gateway socket runs out of attempts.
+// Meanwhile the broadcasts are queued by the socket.
+func (s *Subscriber) follow() []*static.SmartcontractKey {
+	ctx, cancel := context.WithTimeout(context.Background(), s.follow_interval)
+	defer cancel()
+
+	smartcontracts, topicStrings, err := static.RemoteSmartcontractsContext(ctx, s.socket, s.db.TopicFilter())
+	if err != nil {
+		// the SDS Gateway could be unavailable for a while, try it in the next interval.
+		log.Printf("failed to check the redeployed smartcontracts: %v", err)
+		return nil
+	}
+	new_keys, err := s.load_smartcontracts(smartcontracts, topicStrings, false)
+	if err != nil {
+		log.Printf("failed to cache the redeployed smartcontracts: %v", err)
+		return nil
+	}
+	if len(new_keys) > 0 {
+		log.Printf("following the new smartcontracts: %s", strings.Join(generic_type.ToStringList(new_keys), ", "))
+	}
+
+	return new_keys
+}
+
+// Returns true if the reply is a broadcast or a gap of the given smartcontracts.
+func is_broadcast_of(reply *message.Reply, keys []*static.SmartcontractKey) bool {
+	var key static.SmartcontractKey
+	if remote.IsGap(reply) {
+		topic, _, _, err := remote.ParseGap(reply)
+		if err != nil {
+			return false
+		}
+		key = static.SmartcontractKey(topic)
+	} else if reply.IsOK() {
+		network_id, err := message.GetString(reply.Params, "network_id")
+		if err != nil {
+			return false
+		}
+		address, err := message.GetString(reply.Params, "address")
+		if err != nil {
+			return false
+		}
+		key = static.CreateSmartcontractKey(network_id, address)
+	} else {
+		return false
+	}
+
+	for _, following_key := range keys {
+		if *following_key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Passes the new smartcontracts to the running subscription.
+// The subscription, maybe the restarted one, sets the filters then sends the result.
+// Gives up if the subscriber stopped.
+func (s *Subscriber) add_filters(keys []*static.SmartcontractKey, result chan error, stopped chan struct{}) {
+	request := remote.FilterRequest{Topics: generic_type.ToStringList(keys), Result: result}
+	select {
+	case s.filters <- request:
+	case <-stopped:
+	}
+}
+
+// Runs the broadcastSocket.Subscribe() in the background.
+// Use stop_subscription() to stop it.
+func (s *Subscriber) start_subscription(receive_channel chan message.Reply, exit_channel chan int, time_out time.Duration) {
+	subscribed := make(chan struct{})
+	s.subscribed = subscribed
+
+	go func() {
+		s.broadcastSocket.SubscribeWithFilters(receive_channel, exit_channel, s.filters, time_out)
+		close(subscribed)
+	}()
+}
+
+// Stops the broadcastSocket.Subscribe() and waits until it returns.
+// The subscription could already return by itself, for example if the polling failed.
+// After that the broadcastSocket is not used anymore and could be closed.
+func (s *Subscriber) stop_subscription(exit_channel chan int) {
+	select {
+	case exit_channel <- 1:
+	case <-s.subscribed:
+	}
+	<-s.subscribed
 }
 
 func (s *Subscriber) close(exit_channel chan int) error {
 	// Close the previous channel
-	exit_channel <- 1
+	s.stop_subscription(exit_channel)
 
 	return s.broadcastSocket.Close()
 }
 
 // In case of the failure to read the data from the Publisher
-// Or there might be a delay.
+// Or the publisher didn't send the heartbeats.
 // What we do is to reconnect the client to the SDS.
 // Get the snapshot of the missing data, then reconnect the subscriber to read data from SDS Publisher.
 func (s *Subscriber) reconnect(receive_channel chan message.Reply, exit_channel chan int, time_out time.Duration) error {
 	// Close the previous channel
-	exit_channel <- 1
+	s.stop_subscription(exit_channel)
 
 	err := s.broadcastSocket.Close()
 	if err != nil {
@@ -295,7 +460,7 @@ func (s *Subscriber) reconnect(receive_channel chan message.Reply, exit_channel
 		return err
 	}
 
-	go s.broadcastSocket.Subscribe(receive_channel, exit_channel, time_out)
+	s.start_subscription(receive_channel, exit_channel, time_out)
 
 	return nil
 }
@@ -317,15 +482,96 @@ func (s *Subscriber) reconnect(receive_channel chan message.Reply, exit_channel
 func (s *Subscriber) read_from_publisher() error {
 	receive_channel := make(chan message.Reply)
 	exit_channel := make(chan int)
-	time_out := time.Duration(time.Second * 30)
+	s.filters = make(chan remote.FilterRequest)
+	// reconnect only if the publisher missed the heartbeats
+	time_out := remote.HeartbeatTimeout()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	s.start_subscription(receive_channel, exit_channel, time_out)
 
-	go s.broadcastSocket.Subscribe(receive_channel, exit_channel, time_out)
+	// the cached block timestamps of the smartcontracts before the gap.
+	// the missing messages are fetched once the broadcast after the gap arrives.
+	gaps := map[static.SmartcontractKey]uint64{}
+
+	// the nil channel is never ready, if the subscriber doesn't follow the redeployments
+	var follow_channel <-chan time.Time
+	if s.follow_interval > 0 {
+		ticker := time.NewTicker(s.follow_interval)
+		defer ticker.Stop()
+		follow_channel = ticker.C
+	}
+	// the new smartcontracts that are being subscribed, and the result of the subscription.
+	var following []*static.SmartcontractKey
+	var follow_result chan error
+	// the broadcasts of the new smartcontracts, received before their snapshot.
+	// they are handled after the snapshot, otherwise the snapshot would start
+	// after their block timestamp.
+	var pending []message.Reply
 
 	for {
-		reply := <-receive_channel
+		var reply message.Reply
+		if following == nil && len(pending) > 0 {
+			reply = pending[0]
+			pending = pending[1:]
+		} else {
+			select {
+			case reply = <-receive_channel:
+				if following != nil && is_broadcast_of(&reply, following) {
+					pending = append(pending, reply)
+					continue
+				}
+			case <-follow_channel:
+				if following != nil {
+					continue
+				}
+				new_keys := s.follow()
+				if len(new_keys) == 0 {
+					continue
+				}
+				following = new_keys
+				follow_result = make(chan error, 1)
+				go s.add_filters(new_keys, follow_result, stopped)
+				continue
+			case err := <-follow_result:
+				new_keys := following
+				following = nil
+				follow_result = nil
+				if err == nil {
+					// the broadcasts of the new smartcontracts are kept pending meanwhile.
+					err = s.get_snapshot_of(new_keys)
+				}
+				if err != nil {
+					if close_err := s.close(exit_channel); close_err != nil {
+						return errors.New("failed to follow the new smartcontracts: " + err.Error() + ", . failed to close the subscriber loop. error " + close_err.Error())
+					}
+					return errors.New("failed to follow the new smartcontracts: " + err.Error())
+				}
+				continue
+			}
+		}
 
 		if !reply.IsOK() {
-			if reply.Message == "timeout" {
+			if remote.IsGap(&reply) {
+				// the publisher dropped some messages of the smartcontract.
+				// remote.Subscribe() sends the broadcast after the gap right away,
+				// then the missing messages are fetched from the SDS Gateway.
+				topic, sequence_from, sequence_to, err := remote.ParseGap(&reply)
+				if err != nil {
+					if close_err := s.close(exit_channel); close_err != nil {
+						return errors.New("the invalid gap of the subscription: " + err.Error() + ", . failed to close the subscriber loop. error " + close_err.Error())
+					}
+					return errors.New("the invalid gap of the subscription: " + err.Error())
+				}
+				fmt.Println("missed the broadcasts", sequence_from, "-", sequence_to, "of", topic)
+
+				key := static.SmartcontractKey(topic)
+				if _, ok := gaps[key]; !ok {
+					gaps[key] = s.db.GetBlockTimestamp(key)
+				}
+				continue
+			} else if reply.Code == message.TIMEOUT {
 				err := s.reconnect(receive_channel, exit_channel, time_out)
 				if err != nil {
 					return err
@@ -385,13 +631,27 @@ func (s *Subscriber) read_from_publisher() error {
 
 		key := static.CreateSmartcontractKey(networkId, address)
 
+		// fetch the missing messages between the last one before the gap and this one.
+		// the snapshot includes the block of this broadcast, its data is not sent twice.
+		var backfilled map[string]bool
+		if gap_block_timestamp, ok := gaps[key]; ok {
+			delete(gaps, key)
+			backfilled = map[string]bool{}
+			if err := s.get_snapshot_range([]*static.SmartcontractKey{&key}, gap_block_timestamp, block_timestamp, backfilled); err != nil {
+				if close_err := s.close(exit_channel); close_err != nil {
+					return errors.New("failed to backfill the missing messages: " + err.Error() + ", . failed to close the subscriber loop. error " + close_err.Error())
+				}
+				return errors.New("failed to backfill the missing messages: " + err.Error())
+			}
+		}
+
 		// we skip the duplicate messages that were fetched by the Snapshot
 		if s.db.GetBlockTimestamp(key) > block_timestamp {
 			continue
 		}
 
-		transactions := make([]*categorizer.Transaction, len(raw_transactions))
-		for i, raw := range raw_transactions {
+		transactions := make([]*categorizer.Transaction, 0, len(raw_transactions))
+		for _, raw := range raw_transactions {
 			transaction, err := categorizer.ParseTransaction(raw)
 			if err != nil {
 				if close_err := s.close(exit_channel); close_err != nil {
@@ -400,11 +660,13 @@ func (s *Subscriber) read_from_publisher() error {
 				return errors.New("the sds publisher invalid 'transactions'. failed to parse it. error " + err.Error())
 			}
 
-			transactions[i] = transaction
+			if !backfilled[transaction_id(transaction)] {
+				transactions = append(transactions, transaction)
+			}
 		}
 
-		logs := make([]*categorizer.Log, len(raw_logs))
-		for i, raw := range raw_logs {
+		logs := make([]*categorizer.Log, 0, len(raw_logs))
+		for _, raw := range raw_logs {
 			log, err := categorizer.ParseLog(raw)
 			if err != nil {
 				if close_err := s.close(exit_channel); close_err != nil {
@@ -413,7 +675,9 @@ func (s *Subscriber) read_from_publisher() error {
 				return errors.New("the sds publisher invalid 'logs'. failed to parse it. error " + err.Error())
 			}
 
-			logs[i] = log
+			if !backfilled[log_id(log)] {
+				logs = append(logs, log)
+			}
 		}
 
 		// Update the timestamp in the cache only if the received data is valid.
@@ -425,6 +689,11 @@ func (s *Subscriber) read_from_publisher() error {
 			return errors.New("the local cache saving error " + err.Error())
 		}
 
+		// the snapshot already sent all data of the broadcast
+		if backfilled != nil && len(transactions) == 0 && len(logs) == 0 {
+			continue
+		}
+
 		return_reply := message.Reply{
 			Status:  "OK",
 			Message: "",
@@ -439,3 +708,63 @@ func (s *Subscriber) read_from_publisher() error {
 		s.BroadcastChan <- message.NewBroadcast("OK", return_reply)
 	}
 }
+
+// Dispatches the transactions and logs of the broadcast to the handlers of the router.
+// The topic of the transaction is the topic of its smartcontract with the method,
+// the topic of the log is the topic of its smartcontract with the event.
+// The handlers receive *categorizer.Transaction and *categorizer.Log as the data.
+//
+// Returns an error if the broadcast is a failure.
+// The transactions and logs that can't be routed, for example without the cached topic string,
+// are skipped. The rest are dispatched, then the error lists the skipped ones.
+func (s *Subscriber) Dispatch(router *topic.Router, broadcast message.Broadcast) error {
+	reply := broadcast.Reply()
+	if !reply.IsOK() {
+		return errors.New("received an error from subscription: " + reply.Message)
+	}
+
+	skipped := make([]string, 0)
+
+	transactions, _ := reply.Params["transactions"].([]*categorizer.Transaction)
+	for _, transaction := range transactions {
+		key := static.CreateSmartcontractKey(transaction.NetworkId, transaction.Address)
+		if _, err := router.RouteMethod(s.db.GetTopicString(key), transaction.Method, transaction); err != nil {
+			skipped = append(skipped, "the transaction "+transaction.Txid+": "+err.Error())
+		}
+	}
+
+	logs, _ := reply.Params["logs"].([]*categorizer.Log)
+	for _, log := range logs {
+		key := static.CreateSmartcontractKey(log.NetworkId, log.Address)
+		if _, err := router.RouteEvent(s.db.GetTopicString(key), log.Log, log); err != nil {
+			skipped = append(skipped, "the log of "+log.Txid+": "+err.Error())
+		}
+	}
+
+	if len(skipped) > 0 {
+		return errors.New("failed to route " + strings.Join(skipped, "; "))
+	}
+
+	return nil
+}
+
+// Reads the broadcasts of the started subscriber and dispatches them to the router,
+// until the subscriber stops. The BroadcastChan is read till the end, so the subscriber never blocks.
+//
+// The failed broadcasts and the skipped transactions and logs are printed.
+// Returns the last failure of the subscriber, the reason why it stopped.
+func (s *Subscriber) Route(router *topic.Router) error {
+	var last_err error
+	for broadcast := range s.BroadcastChan {
+		err := s.Dispatch(router, broadcast)
+		if err == nil {
+			continue
+		}
+		fmt.Println(err)
+		if !broadcast.IsOK() {
+			last_err = err
+		}
+	}
+
+	return last_err
+}
@@ -1,37 +1,43 @@
 package writer
 
 import (
+	"context"
+
+	"github.com/blocklords/gosds/account"
 	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
 	"github.com/blocklords/gosds/topic"
 )
 
 type Writer struct {
-	socket  *remote.Socket // SDS Gateway host
-	address string         // Account address granted for reading
+	socket  remote.Requester                // SDS Gateway host
+	address string                          // Account address granted for reading
+	signer  *account.SmartcontractDeveloper // optional, signs the requests
 }
 
-func NewWriter(gatewaySocket *remote.Socket, address string) *Writer {
+func NewWriter(gatewaySocket remote.Requester, address string) *Writer {
 	return &Writer{socket: gatewaySocket, address: address}
 }
 
+// Sign all requests by the smartcontract developer with the private key.
+// The requests are sent as message.SmartcontractDeveloperRequest,
+// so the SDS Gateway authenticates the writer.
+func (r *Writer) SetSigner(signer *account.SmartcontractDeveloper) {
+	r.signer = signer
+}
+
 func (r *Writer) Write(t topic.Topic, args map[string]interface{}) message.Reply {
 	if t.Level() != topic.FULL_LEVEL {
 		return message.Fail(`Topic should contain method name`)
 	}
 
-	request := message.Request{
-		Command: "smartcontract_write",
-		Parameters: map[string]interface{}{
-			"topic_string": t.ToString(topic.FULL_LEVEL),
-			"arguments":    args,
-			"address":      r.address,
-		},
-	}
-
-	params, err := r.socket.RequestRemoteService(&request)
+	params, err := r.request("smartcontract_write", map[string]interface{}{
+		"topic_string": t.ToString(topic.FULL_LEVEL),
+		"arguments":    args,
+		"address":      r.address,
+	})
 	if err != nil {
-		return message.Fail(err.Error())
+		return remote.ErrorReply(err)
 	}
 
 	return message.Reply{Status: "OK", Message: "", Params: params}
@@ -42,19 +48,32 @@ func (r *Writer) AddToPool(t topic.Topic, args map[string]interface{}) message.R
 		return message.Fail(`Topic should contain method name`)
 	}
 
-	request := message.Request{
-		Command: "pool_add",
-		Parameters: map[string]interface{}{
-			"topic_string": t.ToString(topic.FULL_LEVEL),
-			"arguments":    args,
-			"address":      r.address,
-		},
-	}
-
-	params, err := r.socket.RequestRemoteService(&request)
+	params, err := r.request("pool_add", map[string]interface{}{
+		"topic_string": t.ToString(topic.FULL_LEVEL),
+		"arguments":    args,
+		"address":      r.address,
+	})
 	if err != nil {
-		return message.Fail(err.Error())
+		return remote.ErrorReply(err)
 	}
 
 	return message.Reply{Status: "OK", Message: "", Params: params}
 }
+
+// Sends the command to the SDS Gateway, signed if the writer has a signer.
+func (r *Writer) request(command string, parameters map[string]interface{}) (map[string]interface{}, error) {
+	if r.signer == nil {
+		request := message.Request{
+			Command:    command,
+			Parameters: parameters,
+		}
+		return r.socket.RequestRemoteService(&request)
+	}
+
+	request := message.SmartcontractDeveloperRequest{
+		Command:    command,
+		Parameters: parameters,
+	}
+	// the write is not resent, since it would be executed twice if only the reply was lost
+	return remote.RequestSigned(context.Background(), r.socket, r.signer, &request, remote.RetryPolicy{MaxAttempts: 1})
+}
@@ -5,15 +5,43 @@ import (
 	"github.com/blocklords/gosds/remote"
 )
 
+// The parameters of the "block_minted_time_get" command
+type BlockMintedTimeRequest struct {
+	NetworkId   string `json:"network_id"`
+	BlockNumber uint64 `json:"block_number"`
+}
+
+// The reply of the "block_minted_time_get" command
+type BlockMintedTimeReply struct {
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// The parameters of the "block_get_range" command.
+// The To is the smartcontract address.
+type BlockRangeRequest struct {
+	BlockNumberFrom uint64 `json:"block_number_from"`
+	BlockNumberTo   uint64 `json:"block_number_to"`
+	To              string `json:"to"`
+	NetworkId       string `json:"network_id"`
+}
+
+// The reply of the "block_get_range" command.
+// Use ParseTransaction() and ParseLog() for the transactions and logs.
+type BlockRangeReply struct {
+	Timestamp    uint64                   `json:"timestamp"`
+	Transactions []map[string]interface{} `json:"transactions"`
+	Logs         []map[string]interface{} `json:"logs"`
+}
+
 // Returns the block minted time from SDS Spaghetti
-func RemoteBlockMintedTime(socket *remote.Socket, networkId string, blockNumber uint64) (uint64, error) {
-	// Send hello.
+func RemoteBlockMintedTime(socket remote.Requester, networkId string, blockNumber uint64) (uint64, error) {
+	parameters, err := message.EncodeParams(BlockMintedTimeRequest{NetworkId: networkId, BlockNumber: blockNumber})
+	if err != nil {
+		return 0, err
+	}
 	request := message.Request{
-		Command: "block_minted_time_get",
-		Parameters: map[string]interface{}{
-			"network_id":   networkId,
-			"block_number": blockNumber,
-		},
+		Command:    "block_minted_time_get",
+		Parameters: parameters,
 	}
 
 	paramseters, err := socket.RequestRemoteService(&request)
@@ -21,42 +49,41 @@ func RemoteBlockMintedTime(socket *remote.Socket, networkId string, blockNumber
 		return 0, err
 	}
 
-	return message.GetUint64(paramseters, "timestamp")
-}
-
-func RemoteBlockRange(socket *remote.Socket, networkId string, address string, from uint64, to uint64) (uint64, []*Transaction, []*Log, error) {
-	request := message.Request{
-		Command: "block_get_range",
-		Parameters: map[string]interface{}{
-			"block_number_from": from,
-			"block_number_to":   to,
-			"to":                address,
-			"network_id":        networkId,
-		},
-	}
-
-	paramseters, err := socket.RequestRemoteService(&request)
+	reply, err := message.DecodeParams[BlockMintedTimeReply](paramseters)
 	if err != nil {
-		return 0, nil, nil, err
+		return 0, err
 	}
 
-	timestamp, err := message.GetUint64(paramseters, "timestamp")
+	return reply.Timestamp, nil
+}
+
+func RemoteBlockRange(socket remote.Requester, networkId string, address string, from uint64, to uint64) (uint64, []*Transaction, []*Log, error) {
+	parameters, err := message.EncodeParams(BlockRangeRequest{
+		BlockNumberFrom: from,
+		BlockNumberTo:   to,
+		To:              address,
+		NetworkId:       networkId,
+	})
 	if err != nil {
 		return 0, nil, nil, err
 	}
+	request := message.Request{
+		Command:    "block_get_range",
+		Parameters: parameters,
+	}
 
-	raw_transactions, err := message.GetMapList(paramseters, "transactions")
+	paramseters, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return 0, nil, nil, err
 	}
 
-	raw_logs, err := message.GetMapList(paramseters, "logs")
+	reply, err := message.DecodeParams[BlockRangeReply](paramseters)
 	if err != nil {
 		return 0, nil, nil, err
 	}
 
-	transactions := make([]*Transaction, len(raw_transactions))
-	for i, raw := range raw_transactions {
+	transactions := make([]*Transaction, len(reply.Transactions))
+	for i, raw := range reply.Transactions {
 		tx, err := ParseTransaction(raw)
 		if err != nil {
 			return 0, nil, nil, err
@@ -64,8 +91,8 @@ func RemoteBlockRange(socket *remote.Socket, networkId string, address string, f
 		transactions[i] = tx
 	}
 
-	logs := make([]*Log, len(raw_logs))
-	for i, raw := range raw_logs {
+	logs := make([]*Log, len(reply.Logs))
+	for i, raw := range reply.Logs {
 		l, err := ParseLog(raw)
 		if err != nil {
 			return 0, nil, nil, err
@@ -73,5 +100,5 @@ func RemoteBlockRange(socket *remote.Socket, networkId string, address string, f
 		logs[i] = l
 	}
 
-	return timestamp, transactions, logs, nil
+	return reply.Timestamp, transactions, logs, nil
 }
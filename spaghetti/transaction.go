@@ -4,6 +4,7 @@ package spaghetti
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
 
 	"github.com/blocklords/gosds/message"
 	"github.com/blocklords/gosds/remote"
@@ -17,8 +18,8 @@ type Transaction struct {
 	TxFrom         string
 	TxTo           string
 	TxIndex        uint
-	Data           string  // text Data type
-	Value          float64 // Value attached with transaction
+	Data           string   // text Data type
+	Value          *big.Int // Value attached with transaction in wei
 }
 
 // JSON representation of the spaghetti.Transaction
@@ -32,10 +33,19 @@ func (b *Transaction) ToJSON() map[string]interface{} {
 		"tx_to":           b.TxTo,
 		"tx_index":        b.TxIndex,
 		"tx_Data":         b.Data,
-		"tx_Value":        b.Value,
+		"tx_Value":        value_string(b.Value),
 	}
 }
 
+// The value as a decimal string, since the JSON number would lose the precision of wei.
+// The nil value is zero.
+func value_string(value *big.Int) string {
+	if value == nil {
+		return "0"
+	}
+	return value.String()
+}
+
 // JSON string representation of the spaghetti.Transaction
 func (b *Transaction) ToString() string {
 	interfaces := b.ToJSON()
@@ -81,7 +91,7 @@ func ParseTransaction(parameters map[string]interface{}) (*Transaction, error) {
 	if err != nil {
 		return nil, err
 	}
-	Value, err := message.GetFloat64(parameters, "tx_Value")
+	Value, err := message.GetBigInt(parameters, "tx_Value")
 	if err != nil {
 		return nil, err
 	}
@@ -118,16 +128,30 @@ func ParseTransactions(txs []interface{}) ([]*Transaction, error) {
 	return transactions, nil
 }
 
+// The parameters of the "transaction_deployed_get" command
+type TransactionDeployedRequest struct {
+	NetworkId string `json:"network_id"`
+	Txid      string `json:"txid"`
+}
+
+// The reply of the "transaction_deployed_get" command
+type TransactionDeployedReply struct {
+	Address        string `json:"address"`
+	Deployer       string `json:"deployer"`
+	BlockNumber    uint64 `json:"block_number"`
+	BlockTimestamp uint64 `json:"block_timestamp"`
+}
+
 // Sends the command to the remote SDS Spaghetti to get the smartcontract deploy metaData by
 // its transaction id
-func RemoteTransactionDeployed(socket *remote.Socket, network_id string, Txid string) (string, string, uint64, uint64, error) {
-	// Send hello.
+func RemoteTransactionDeployed(socket remote.Requester, network_id string, Txid string) (string, string, uint64, uint64, error) {
+	parameters, err := message.EncodeParams(TransactionDeployedRequest{NetworkId: network_id, Txid: Txid})
+	if err != nil {
+		return "", "", 0, 0, err
+	}
 	request := message.Request{
-		Command: "transaction_deployed_get",
-		Parameters: map[string]interface{}{
-			"network_id": network_id,
-			"txid":       Txid,
-		},
+		Command:    "transaction_deployed_get",
+		Parameters: parameters,
 	}
 
 	params, err := socket.RequestRemoteService(&request)
@@ -135,22 +159,10 @@ func RemoteTransactionDeployed(socket *remote.Socket, network_id string, Txid st
 		return "", "", 0, 0, err
 	}
 
-	address, err := message.GetString(params, "address")
-	if err != nil {
-		return "", "", 0, 0, err
-	}
-	deployer, err := message.GetString(params, "deployer")
-	if err != nil {
-		return "", "", 0, 0, err
-	}
-	block_number, err := message.GetUint64(params, "block_number")
-	if err != nil {
-		return "", "", 0, 0, err
-	}
-	block_timestamp, err := message.GetUint64(params, "block_timestamp")
+	reply, err := message.DecodeParams[TransactionDeployedReply](params)
 	if err != nil {
 		return "", "", 0, 0, err
 	}
 
-	return address, deployer, block_number, block_timestamp, nil
+	return reply.Address, reply.Deployer, reply.BlockNumber, reply.BlockTimestamp, nil
 }
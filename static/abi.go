@@ -2,7 +2,6 @@ package static
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 
 	"github.com/blocklords/gosds/message"
@@ -71,27 +70,44 @@ func NewAbiFromBytes(bytes []byte) *Abi {
 	return &abi
 }
 
+// The parameters of the "abi_register" command
+type AbiRegisterRequest struct {
+	Abi interface{} `json:"abi"`
+}
+
+// The parameters of the "abi_get" command
+type AbiRequest struct {
+	AbiHash string `json:"abi_hash"`
+}
+
+// The reply of the "abi_get" command
+type AbiReply struct {
+	Abi interface{} `json:"abi"`
+}
+
 // Sends the ABI information to the remote SDS Static.
-func RemoteAbiRegister(socket *remote.Socket, body interface{}) (map[string]interface{}, error) {
-	// Send hello.
+func RemoteAbiRegister(socket remote.Requester, body interface{}) (map[string]interface{}, error) {
+	parameters, err := message.EncodeParams(AbiRegisterRequest{Abi: body})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "abi_register",
-		Parameters: map[string]interface{}{
-			"abi": body,
-		},
+		Command:    "abi_register",
+		Parameters: parameters,
 	}
 
 	return socket.RequestRemoteService(&request)
 }
 
 // Returns the abi from the remote server
-func RemoteAbi(socket *remote.Socket, abi_hash string) (*Abi, error) {
-	// Send hello.
+func RemoteAbi(socket remote.Requester, abi_hash string) (*Abi, error) {
+	parameters, err := message.EncodeParams(AbiRequest{AbiHash: abi_hash})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "abi_get",
-		Parameters: map[string]interface{}{
-			"abi_hash": abi_hash,
-		},
+		Command:    "abi_get",
+		Parameters: parameters,
 	}
 
 	params, err := socket.RequestRemoteService(&request)
@@ -99,12 +115,12 @@ func RemoteAbi(socket *remote.Socket, abi_hash string) (*Abi, error) {
 		return nil, err
 	}
 
-	abi_bytes, ok := params["abi"]
-	if !ok {
-		return nil, errors.New("missing 'abi' parameter from the SDS Static 'abi_get' command")
+	reply, err := message.DecodeParams[AbiReply](params)
+	if err != nil {
+		return nil, err
 	}
 
-	new_abi, err := NewAbi(abi_bytes)
+	new_abi, err := NewAbi(reply.Abi)
 	if err != nil {
 		return nil, err
 	}
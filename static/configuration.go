@@ -15,6 +15,7 @@ type Configuration struct {
 	NetworkId    string
 	Group        string
 	Name         string
+	Version      string // optional, the deployment of the smartcontract, see topic.Topic.Version
 	Address      string
 	id           uint
 	exists       bool
@@ -64,6 +65,10 @@ func NewConfiguration(parameters map[string]interface{}) (*Configuration, error)
 		Name:         smartcontract_name,
 		exists:       true,
 	}
+	version, err := message.GetString(parameters, "v")
+	if err == nil {
+		conf.Version = version
+	}
 	address, err := message.GetString(parameters, "address")
 	if err == nil {
 		conf.SetAddress(address)
@@ -78,7 +83,7 @@ func NewConfiguration(parameters map[string]interface{}) (*Configuration, error)
 
 // JSON representation of the static.Configuration
 func (c *Configuration) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	json := map[string]interface{}{
 		"s":       c.Name,
 		"n":       c.NetworkId,
 		"g":       c.Group,
@@ -86,6 +91,17 @@ func (c *Configuration) ToJSON() map[string]interface{} {
 		"p":       c.Project,
 		"address": c.Address,
 	}
+	if len(c.Version) > 0 {
+		json["v"] = c.Version
+	}
+	return json
+}
+
+// The topic of the configuration, at the smartcontract level
+func (c *Configuration) Topic() topic.Topic {
+	t := topic.New(c.Organization, c.Project, c.NetworkId, c.Group, c.Name, "", "")
+	t.Version = c.Version
+	return t
 }
 
 // static.Configuration as a JSON string
@@ -99,9 +115,15 @@ func (c *Configuration) ToString() string {
 	return string(byt)
 }
 
+// The reply of the "configuration_get" command.
+// Use NewConfiguration() and NewSmartcontract() for the fields.
+type ConfigurationReply struct {
+	Configuration map[string]interface{} `json:"configuration"`
+	Smartcontract map[string]interface{} `json:"smartcontract"`
+}
+
 // get configuration from SDS Static by the configuration topic
-func RemoteConfiguration(socket *remote.Socket, t *topic.Topic) (*Configuration, *Smartcontract, error) {
-	// Send hello.
+func RemoteConfiguration(socket remote.Requester, t *topic.Topic) (*Configuration, *Smartcontract, error) {
 	request := message.Request{
 		Command:    "configuration_get",
 		Parameters: t.ToJSON(),
@@ -111,19 +133,15 @@ func RemoteConfiguration(socket *remote.Socket, t *topic.Topic) (*Configuration,
 		return nil, nil, err
 	}
 
-	raw_configuration, err := message.GetMap(parameters, "configuration")
+	reply, err := message.DecodeParams[ConfigurationReply](parameters)
 	if err != nil {
 		return nil, nil, err
 	}
-	raw_smartcontract, err := message.GetMap(parameters, "smartcontract")
+	conf, err := NewConfiguration(reply.Configuration)
 	if err != nil {
 		return nil, nil, err
 	}
-	conf, err := NewConfiguration(raw_configuration)
-	if err != nil {
-		return nil, nil, err
-	}
-	smartcontract, err := NewSmartcontract(raw_smartcontract)
+	smartcontract, err := NewSmartcontract(reply.Smartcontract)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -132,7 +150,7 @@ func RemoteConfiguration(socket *remote.Socket, t *topic.Topic) (*Configuration,
 }
 
 // Send a command to the SDS Static to register a new configuration
-func RemoteConfigurationRegister(socket *remote.Socket, conf *Configuration) error {
+func RemoteConfigurationRegister(socket remote.Requester, conf *Configuration) error {
 	// Send hello.
 	request := message.Request{
 		Command:    "configuration_register",
@@ -142,3 +160,43 @@ func RemoteConfigurationRegister(socket *remote.Socket, conf *Configuration) err
 	_, err := socket.RequestRemoteService(&request)
 	return err
 }
+
+// The deployment of the smartcontract in the address history of the topic
+type ConfigurationVersion struct {
+	Version                 string `json:"v"`
+	Address                 string `json:"address"`
+	PreDeployBlockTimestamp uint64 `json:"pre_deploy_block_timestamp,omitempty"`
+}
+
+// The reply of the "configuration_history" command.
+// The versions are ordered from the oldest to the latest deployment.
+type ConfigurationHistoryReply struct {
+	History []ConfigurationVersion `json:"history"`
+}
+
+// Returns the addresses of the smartcontract topic, when the smartcontract was redeployed or upgraded.
+// The versions are ordered from the oldest to the latest deployment.
+//
+// The version of the topic is ignored.
+func RemoteConfigurationHistory(socket remote.Requester, t *topic.Topic) ([]ConfigurationVersion, error) {
+	smartcontract_topic := *t
+	smartcontract_topic.Version = ""
+	smartcontract_topic.Method = ""
+	smartcontract_topic.Event = ""
+
+	request := message.Request{
+		Command:    "configuration_history",
+		Parameters: smartcontract_topic.ToJSON(),
+	}
+	parameters, err := socket.RequestRemoteService(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := message.DecodeParams[ConfigurationHistoryReply](parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.History, nil
+}
@@ -0,0 +1,216 @@
+package static
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/blocklords/gosds/remote"
+	"github.com/blocklords/gosds/topic"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// The argument of the smartcontract method or event
+type AbiArgument struct {
+	Name    string
+	Type    string // solidity type, for example "uint256" or "address[]"
+	Indexed bool   // only for the event arguments
+}
+
+// The smartcontract method described by the abi
+type AbiMethod struct {
+	Name            string // the method path of the topic, the same for the overloaded methods
+	Signature       string // for example "transfer(address,uint256)", distinguishes the overloaded methods
+	StateMutability string // "pure", "view", "nonpayable" or "payable"
+	Inputs          []AbiArgument
+	Outputs         []AbiArgument
+}
+
+// The smartcontract event described by the abi
+type AbiEvent struct {
+	Name      string // the event path of the topic, the same for the overloaded events
+	Signature string // for example "Transfer(address,address,uint256)", distinguishes the overloaded events
+	Inputs    []AbiArgument
+}
+
+// The smartcontract found by the topic, along with its methods and events
+type SmartcontractInterface struct {
+	Topic         topic.Topic // the topic at the smartcontract level
+	Smartcontract *Smartcontract
+	Methods       []AbiMethod
+	Events        []AbiEvent
+}
+
+func abi_arguments(arguments abi.Arguments) []AbiArgument {
+	list := make([]AbiArgument, len(arguments))
+	for i, argument := range arguments {
+		list[i] = AbiArgument{
+			Name:    argument.Name,
+			Type:    argument.Type.String(),
+			Indexed: argument.Indexed,
+		}
+	}
+	return list
+}
+
+// Returns the methods of the abi sorted by the name, then by the signature.
+func (a *Abi) Methods() ([]AbiMethod, error) {
+	geth_abi, err := abi.JSON(bytes.NewReader(a.Bytes))
+	if err != nil {
+		return nil, errors.New("failed to parse the abi " + a.AbiHash + ": " + err.Error())
+	}
+
+	methods := make([]AbiMethod, 0, len(geth_abi.Methods))
+	for _, method := range geth_abi.Methods {
+		methods = append(methods, AbiMethod{
+			Name:            method.RawName,
+			Signature:       method.Sig,
+			StateMutability: method.StateMutability,
+			Inputs:          abi_arguments(method.Inputs),
+			Outputs:         abi_arguments(method.Outputs),
+		})
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Name != methods[j].Name {
+			return methods[i].Name < methods[j].Name
+		}
+		return methods[i].Signature < methods[j].Signature
+	})
+
+	return methods, nil
+}
+
+// Returns the events of the abi sorted by the name, then by the signature.
+func (a *Abi) Events() ([]AbiEvent, error) {
+	geth_abi, err := abi.JSON(bytes.NewReader(a.Bytes))
+	if err != nil {
+		return nil, errors.New("failed to parse the abi " + a.AbiHash + ": " + err.Error())
+	}
+
+	events := make([]AbiEvent, 0, len(geth_abi.Events))
+	for _, event := range geth_abi.Events {
+		events = append(events, AbiEvent{
+			Name:      event.RawName,
+			Signature: event.Sig,
+			Inputs:    abi_arguments(event.Inputs),
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Name != events[j].Name {
+			return events[i].Name < events[j].Name
+		}
+		return events[i].Signature < events[j].Signature
+	})
+
+	return events, nil
+}
+
+// The topic filter of the set paths of the partial topic.
+// The method and the event are ignored.
+func partial_filter(t *topic.Topic) topic.TopicFilter {
+	filter := topic.TopicFilter{}
+	if len(t.Organization) > 0 {
+		filter.Organizations = []string{t.Organization}
+	}
+	if len(t.Project) > 0 {
+		filter.Projects = []string{t.Project}
+	}
+	if len(t.NetworkId) > 0 {
+		filter.NetworkIds = []string{t.NetworkId}
+	}
+	if len(t.Group) > 0 {
+		filter.Groups = []string{t.Group}
+	}
+	if len(t.Smartcontract) > 0 {
+		filter.Smartcontracts = []string{t.Smartcontract}
+	}
+	if len(t.Version) > 0 {
+		filter.Versions = []string{t.Version}
+	}
+	return filter
+}
+
+// Returns the smartcontracts and their topics that match the partial topic.
+// The partial topic has only some paths, for example organization and project.
+func RemoteTopics(socket remote.Requester, t *topic.Topic) ([]*Smartcontract, []topic.Topic, error) {
+	filter := partial_filter(t)
+	smartcontracts, topic_strings, err := RemoteSmartcontracts(socket, &filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	topics := make([]topic.Topic, len(topic_strings))
+	for i, topic_string := range topic_strings {
+		topics[i], err = topic.ParseString(topic_string)
+		if err != nil {
+			return nil, nil, errors.New("invalid topic string '" + topic_string + "' of the smartcontract: " + err.Error())
+		}
+	}
+
+	return smartcontracts, topics, nil
+}
+
+// Returns the sorted list of the groups that match the partial topic.
+func RemoteGroups(socket remote.Requester, t *topic.Topic) ([]string, error) {
+	_, topics, err := RemoteTopics(socket, t)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := map[string]bool{}
+	groups := make([]string, 0)
+	for _, smartcontract_topic := range topics {
+		if !exists[smartcontract_topic.Group] {
+			exists[smartcontract_topic.Group] = true
+			groups = append(groups, smartcontract_topic.Group)
+		}
+	}
+	sort.Strings(groups)
+
+	return groups, nil
+}
+
+// Returns the smartcontracts that match the partial topic, along with their methods and events.
+// The methods and events are derived from the abi of the smartcontract stored in the SDS Static.
+func RemoteSmartcontractInterfaces(socket remote.Requester, t *topic.Topic) ([]*SmartcontractInterface, error) {
+	smartcontracts, topics, err := RemoteTopics(socket, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// the smartcontracts could share the same abi
+	abis := map[string]*Abi{}
+	interfaces := make([]*SmartcontractInterface, len(smartcontracts))
+	for i, smartcontract := range smartcontracts {
+		smartcontract_abi, ok := abis[smartcontract.AbiHash]
+		if !ok {
+			smartcontract_abi, err = RemoteAbi(socket, smartcontract.AbiHash)
+			if err != nil {
+				return nil, err
+			}
+			abis[smartcontract.AbiHash] = smartcontract_abi
+		}
+
+		methods, err := smartcontract_abi.Methods()
+		if err != nil {
+			return nil, err
+		}
+		events, err := smartcontract_abi.Events()
+		if err != nil {
+			return nil, err
+		}
+
+		interfaces[i] = &SmartcontractInterface{
+			Topic:         topics[i],
+			Smartcontract: smartcontract,
+			Methods:       methods,
+			Events:        events,
+		}
+	}
+
+	sort.SliceStable(interfaces, func(i, j int) bool {
+		return interfaces[i].Topic.ToString(topic.SMARTCONTRACT_LEVEL) < interfaces[j].Topic.ToString(topic.SMARTCONTRACT_LEVEL)
+	})
+
+	return interfaces, nil
+}
@@ -81,7 +81,7 @@ func (n *Network) ToJSON() map[string]interface{} {
 }
 
 // Returns list of the supported networks from SDS Static
-func GetSupportedNetworks(static_socket *remote.Socket, flag int8) map[string]string {
+func GetSupportedNetworks(static_socket remote.Requester, flag int8) map[string]string {
 	env := os.Getenv("SUPPORTED_NETWORKS")
 	if len(env) == 0 {
 		panic("the environment variable 'SUPPORTED_NETWORKS' is not provided")
@@ -115,7 +115,7 @@ func GetSupportedNetworks(static_socket *remote.Socket, flag int8) map[string]st
 }
 
 // Returns list of support network IDs from SDS Static
-func GetNetworkIds(socket *remote.Socket, flag int8) ([]string, error) {
+func GetNetworkIds(socket remote.Requester, flag int8) ([]string, error) {
 	if !IsValidFlag(flag) {
 		return nil, errors.New("invalid 'flag' parameter")
 	}
@@ -134,7 +134,7 @@ func GetNetworkIds(socket *remote.Socket, flag int8) ([]string, error) {
 }
 
 // Returns list of support network IDs from SDS Static
-func GetNetworks(socket *remote.Socket, flag int8) ([]*Network, error) {
+func GetNetworks(socket remote.Requester, flag int8) ([]*Network, error) {
 	if !IsValidFlag(flag) {
 		return nil, errors.New("invalid 'flag' parameter")
 	}
@@ -158,7 +158,7 @@ func GetNetworks(socket *remote.Socket, flag int8) ([]*Network, error) {
 }
 
 // Returns the Blockchain Network access provider
-func GetNetwork(socket *remote.Socket, network_id string, flag int8) (*Network, error) {
+func GetNetwork(socket remote.Requester, network_id string, flag int8) (*Network, error) {
 	if !IsValidFlag(flag) {
 		return nil, errors.New("invalid 'flag' parameter")
 	}
@@ -1,6 +1,7 @@
 package static
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -123,33 +124,79 @@ func (smartcontract *Smartcontract) ToString() string {
 	return string(byt)
 }
 
+// The parameters of the "smartcontract_filter" and "smartcontract_key_filter" commands
+type SmartcontractFilterRequest struct {
+	TopicFilter map[string]interface{} `json:"topic_filter"`
+}
+
+// The reply of the "smartcontract_filter" command.
+// The topic strings are in the same order as smartcontracts.
+// Use NewSmartcontract() for each of the smartcontracts.
+type SmartcontractFilterReply struct {
+	Smartcontracts []map[string]interface{} `json:"smartcontracts"`
+	Topics         []string                 `json:"topics"`
+}
+
+// The reply of the "smartcontract_key_filter" command
+type SmartcontractKeyFilterReply struct {
+	SmartcontractKeys map[string]string `json:"smartcontract_keys"`
+}
+
+// The parameters of the "smartcontract_get" command
+type SmartcontractRequest struct {
+	NetworkId string `json:"network_id"`
+	Address   string `json:"address"`
+}
+
+// The reply of the "smartcontract_get" command.
+// Use NewSmartcontract() for the smartcontract.
+type SmartcontractReply struct {
+	Smartcontract map[string]interface{} `json:"smartcontract"`
+}
+
+// The reply of the "smartcontract_register" command
+type SmartcontractRegisterReply struct {
+	Address string `json:"address"`
+}
+
 // Returns list of smartcontracts by topic filter in remote Static service
 // also the topic path of the smartcontract
-func RemoteSmartcontracts(socket *remote.Socket, tf *topic.TopicFilter) ([]*Smartcontract, []string, error) {
-	request := message.Request{
-		Command: "smartcontract_filter",
-		Parameters: map[string]interface{}{
-			"topic_filter": tf.ToJSON(),
-		},
+func RemoteSmartcontracts(socket remote.Requester, tf *topic.TopicFilter) ([]*Smartcontract, []string, error) {
+	return remote_smartcontracts(socket.RequestRemoteService, tf)
+}
+
+// Returns the smartcontracts by topic filter the same way as RemoteSmartcontracts().
+// The request gives up as soon as the context is done, see remote.Requester.RequestRemoteServiceContext().
+func RemoteSmartcontractsContext(ctx context.Context, socket remote.Requester, tf *topic.TopicFilter) ([]*Smartcontract, []string, error) {
+	request_remote_service := func(request *message.Request) (map[string]interface{}, error) {
+		return socket.RequestRemoteServiceContext(ctx, request)
 	}
-	params, err := socket.RequestRemoteService(&request)
+	return remote_smartcontracts(request_remote_service, tf)
+}
+
+func remote_smartcontracts(request_remote_service func(*message.Request) (map[string]interface{}, error), tf *topic.TopicFilter) ([]*Smartcontract, []string, error) {
+	parameters, err := message.EncodeParams(SmartcontractFilterRequest{TopicFilter: tf.ToJSON()})
 	if err != nil {
 		return nil, nil, err
 	}
-
-	raw_smartcontracts, err := message.GetMapList(params, "smartcontracts")
+	request := message.Request{
+		Command:    "smartcontract_filter",
+		Parameters: parameters,
+	}
+	params, err := request_remote_service(&request)
 	if err != nil {
 		return nil, nil, err
 	}
-	topic_strings, err := message.GetStringList(params, "topics")
+
+	reply, err := message.DecodeParams[SmartcontractFilterReply](params)
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(raw_smartcontracts) != len(topic_strings) {
+	if len(reply.Smartcontracts) != len(reply.Topics) {
 		return nil, nil, errors.New("the returned amount of topic strings mismatch with smartcontracts")
 	}
-	var smartcontracts []*Smartcontract = make([]*Smartcontract, len(raw_smartcontracts))
-	for i, raw_smartcontract := range raw_smartcontracts {
+	var smartcontracts []*Smartcontract = make([]*Smartcontract, len(reply.Smartcontracts))
+	for i, raw_smartcontract := range reply.Smartcontracts {
 		smartcontract, err := NewSmartcontract(raw_smartcontract)
 		if err != nil {
 			return nil, nil, err
@@ -157,33 +204,30 @@ func RemoteSmartcontracts(socket *remote.Socket, tf *topic.TopicFilter) ([]*Smar
 		smartcontracts[i] = smartcontract
 	}
 
-	return smartcontracts, topic_strings, nil
+	return smartcontracts, reply.Topics, nil
 }
 
 // returns list of smartcontract keys by topic filter
-func RemoteSmartcontractKeys(socket *remote.Socket, tf *topic.TopicFilter) (FilteredSmartcontractKeys, error) {
-	// Send hello.
+func RemoteSmartcontractKeys(socket remote.Requester, tf *topic.TopicFilter) (FilteredSmartcontractKeys, error) {
+	parameters, err := message.EncodeParams(SmartcontractFilterRequest{TopicFilter: tf.ToJSON()})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "smartcontract_key_filter",
-		Parameters: map[string]interface{}{
-			"topic_filter": tf.ToJSON(),
-		},
+		Command:    "smartcontract_key_filter",
+		Parameters: parameters,
 	}
 	params, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	raw_keys, err := message.GetMap(params, "smartcontract_keys")
+	reply, err := message.DecodeParams[SmartcontractKeyFilterReply](params)
 	if err != nil {
 		return nil, err
 	}
-	var keys FilteredSmartcontractKeys = make(FilteredSmartcontractKeys, len(raw_keys))
-	for key, raw_value := range raw_keys {
-		topic_string, ok := raw_value.(string)
-		if !ok {
-			return nil, errors.New("one of the topic strings is not in the string format")
-		}
+	var keys FilteredSmartcontractKeys = make(FilteredSmartcontractKeys, len(reply.SmartcontractKeys))
+	for key, topic_string := range reply.SmartcontractKeys {
 		keys[SmartcontractKey(key)] = topic_string
 	}
 
@@ -191,29 +235,28 @@ func RemoteSmartcontractKeys(socket *remote.Socket, tf *topic.TopicFilter) (Filt
 }
 
 // returns smartcontract by smartcontract key from SDS Static
-func RemoteSmartcontract(socket *remote.Socket, network_id string, address string) (*Smartcontract, error) {
-	// Send hello.
+func RemoteSmartcontract(socket remote.Requester, network_id string, address string) (*Smartcontract, error) {
+	parameters, err := message.EncodeParams(SmartcontractRequest{NetworkId: network_id, Address: address})
+	if err != nil {
+		return nil, err
+	}
 	request := message.Request{
-		Command: "smartcontract_get",
-		Parameters: map[string]interface{}{
-			"network_id": network_id,
-			"address":    address,
-		},
+		Command:    "smartcontract_get",
+		Parameters: parameters,
 	}
 	params, err := socket.RequestRemoteService(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	raw_smartcontract, err := message.GetMap(params, "smartcontract")
+	reply, err := message.DecodeParams[SmartcontractReply](params)
 	if err != nil {
 		return nil, err
 	}
-	return NewSmartcontract(raw_smartcontract)
+	return NewSmartcontract(reply.Smartcontract)
 }
 
-func RemoteSmartcontractRegister(socket *remote.Socket, s *Smartcontract) (string, error) {
-	// Send hello.
+func RemoteSmartcontractRegister(socket remote.Requester, s *Smartcontract) (string, error) {
 	request := message.Request{
 		Command:    "smartcontract_register",
 		Parameters: s.ToJSON(),
@@ -224,5 +267,10 @@ func RemoteSmartcontractRegister(socket *remote.Socket, s *Smartcontract) (strin
 		return "", err
 	}
 
-	return message.GetString(params, "address")
+	reply, err := message.DecodeParams[SmartcontractRegisterReply](params)
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Address, nil
 }
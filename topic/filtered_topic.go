@@ -1,13 +1,30 @@
 package topic
 
-import "github.com/blocklords/gosds/message"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/blocklords/gosds/message"
+)
+
+// The topic filter selects the topics by the list of values per path.
+//
+// The value of the path is either a literal, or a pattern:
+//
+//   - `*` matches any sequence of characters, `s:Scape*` matches Scape, ScapeNft, ScapeStaking.
+//   - `!` at the beginning negates the value, `g:!test` matches any group except test.
+//
+// The topic matches the path, if it matches any of the non negated values (if there are any),
+// and it doesn't match any of the negated values. The empty path matches all topics.
 type TopicFilter struct {
 	Organizations  []string
 	Projects       []string
 	NetworkIds     []string
 	Groups         []string
 	Smartcontracts []string
+	Versions       []string // the deployments of the smartcontracts, see Topic.Version
 	Methods        []string
 	Events         []string
 }
@@ -24,16 +41,19 @@ func NewFilterTopic(o []string, p []string, n []string, g []string, s []string,
 	}
 }
 
+// Returns the canonical JSON object of the topic filter.
+// Only the paths with the values are included, see Canonical().
 func (t *TopicFilter) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
-		"o":  t.Organizations,
-		"p:": t.Projects,
-		"n":  t.NetworkIds,
-		"g":  t.Groups,
-		"s":  t.Smartcontracts,
-		"m":  t.Methods,
-		"e":  t.Events,
+	canonical := t.Canonical()
+	json := map[string]interface{}{}
+	for _, pathName := range path_names {
+		values := *canonical.path(pathName)
+		if len(values) > 0 {
+			json[pathName] = values
+		}
 	}
+
+	return json
 }
 
 func (t *TopicFilter) Len(level uint8) int {
@@ -51,7 +71,7 @@ func (t *TopicFilter) Len(level uint8) int {
 	case FULL_LEVEL:
 		return len(t.Methods) + len(t.Events)
 	default:
-		return len(t.Organizations) + len(t.Projects) + len(t.NetworkIds) + len(t.Groups) + len(t.Smartcontracts) + len(t.Methods) + len(t.Events)
+		return len(t.Organizations) + len(t.Projects) + len(t.NetworkIds) + len(t.Groups) + len(t.Smartcontracts) + len(t.Versions) + len(t.Methods) + len(t.Events)
 	}
 }
 
@@ -60,84 +80,247 @@ func (t *TopicFilter) Key() TopicKey {
 	return TopicKey(t.ToString())
 }
 
-// list of path
-func list(properties []string) string {
-	str := ""
-	for _, v := range properties {
-		str += "," + v
+// The path names in the order of the topic string
+var path_names = []string{"o", "p", "n", "g", "s", "v", "m", "e"}
+
+// Returns the copy of the topic filter in the canonical form:
+// the values of every path are sorted and the duplicates are removed.
+//
+// The filters with the same values of every path, regardless of their order and duplicates,
+// have the same canonical form, and therefore the same ToString(), ToJSON() and Key().
+// The filters could select the same topics by the different values,
+// for example `s:*` and the empty path. Their canonical forms differ.
+func (t *TopicFilter) Canonical() TopicFilter {
+	canonical := TopicFilter{}
+	for _, pathName := range path_names {
+		values := *t.path(pathName)
+		if len(values) == 0 {
+			continue
+		}
+
+		sorted := make([]string, len(values))
+		copy(sorted, values)
+		sort.Strings(sorted)
+
+		unique := sorted[:1]
+		for _, value := range sorted[1:] {
+			if value != unique[len(unique)-1] {
+				unique = append(unique, value)
+			}
+		}
+		*canonical.path(pathName) = unique
 	}
 
-	return str
+	return canonical
 }
 
-// Convert the topic filter object to the topic filter string.
+// Convert the topic filter object to the canonical topic filter string.
+//
+// The paths are in the order o, p, n, g, s, v, m, e separated by `;`, the paths without values are skipped.
+// The values are sorted and separated by `,`. For example:
+//
+//	o:seascape;g:ERC20,nft;e:Burn,Mint
+//
+// The filter string is parsed back by ParseFilterString(), that returns the canonical filter.
 func (t *TopicFilter) ToString() string {
-	str := ""
-	if len(t.Organizations) > 0 {
-		str += "o:" + list(t.Organizations) + ";"
+	canonical := t.Canonical()
+	paths := make([]string, 0, len(path_names))
+	for _, pathName := range path_names {
+		values := *canonical.path(pathName)
+		if len(values) > 0 {
+			paths = append(paths, pathName+":"+strings.Join(values, ","))
+		}
 	}
-	if len(t.Projects) > 0 {
-		str += "p:" + list(t.Projects) + ";"
-	}
-	if len(t.NetworkIds) > 0 {
-		str += "n:" + list(t.NetworkIds) + ";"
-	}
-	if len(t.Groups) > 0 {
-		str += "g:" + list(t.Groups) + ";"
+
+	return strings.Join(paths, ";")
+}
+
+// Converts the JSON object to the topic.TopicFilter.
+// The missing or null paths are empty.
+//
+// The topic filter is returned in the canonical form, therefore
+// ParseJSONToTopicFilter(filter.ToJSON()) equals to filter.Canonical().
+func ParseJSONToTopicFilter(parameters map[string]interface{}) (*TopicFilter, error) {
+	topic_filter := TopicFilter{}
+
+	for _, pathName := range path_names {
+		key := pathName
+		// the older SDK versions sent the projects as "p:"
+		if _, ok := parameters[key]; !ok && pathName == "p" {
+			key = "p:"
+		}
+		if raw, ok := parameters[key]; !ok || raw == nil {
+			continue
+		}
+		values, err := message.GetStringList(parameters, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if !isFilterValue(value) {
+				return nil, fmt.Errorf("invalid value for path name '%s': %s", pathName, value)
+			}
+		}
+		*topic_filter.path(pathName) = values
 	}
-	if len(t.Smartcontracts) > 0 {
-		str += "s:" + list(t.Smartcontracts) + ";"
+
+	canonical := topic_filter.Canonical()
+	return &canonical, nil
+}
+
+// Returns true if the topic matches the filter.
+//
+// The method and event paths of the filter are checked only if the topic has a method or an event.
+// The method topic doesn't match the filter that has only the events, and vice versa.
+func (t *TopicFilter) Match(topic Topic) bool {
+	if !match_path(t.Organizations, topic.Organization) ||
+		!match_path(t.Projects, topic.Project) ||
+		!match_path(t.NetworkIds, topic.NetworkId) ||
+		!match_path(t.Groups, topic.Group) ||
+		!match_path(t.Smartcontracts, topic.Smartcontract) ||
+		!match_path(t.Versions, topic.Version) {
+		return false
 	}
-	if len(t.Methods) > 0 {
-		str += "m:" + list(t.Methods) + ";"
+
+	if len(topic.Method) > 0 {
+		if len(t.Methods) == 0 {
+			return len(t.Events) == 0
+		}
+		return match_path(t.Methods, topic.Method)
 	}
-	if len(t.Events) > 0 {
-		str += "e:" + list(t.Events) + ";"
+	if len(topic.Event) > 0 {
+		if len(t.Events) == 0 {
+			return len(t.Methods) == 0
+		}
+		return match_path(t.Events, topic.Event)
 	}
 
-	return str
+	return true
 }
 
-// Converts the JSON object to the topic.TopicFilter
-func ParseJSONToTopicFilter(parameters map[string]interface{}) (*TopicFilter, error) {
-	topic_filter := TopicFilter{
-		Organizations:  []string{},
-		Projects:       []string{},
-		NetworkIds:     []string{},
-		Groups:         []string{},
-		Smartcontracts: []string{},
-		Methods:        []string{},
-		Events:         []string{},
+// Returns true if the value matches the path values of the filter.
+func match_path(patterns []string, value string) bool {
+	has_included := false
+	included := false
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if match_glob(pattern[1:], value) {
+				return false
+			}
+		} else {
+			has_included = true
+			if !included && match_glob(pattern, value) {
+				included = true
+			}
+		}
 	}
 
-	organizations, err := message.GetStringList(parameters, "o")
-	if err == nil {
-		topic_filter.Organizations = organizations
-	}
-	projects, err := message.GetStringList(parameters, "p")
-	if err == nil {
-		topic_filter.Projects = projects
-	}
-	network_ids, err := message.GetStringList(parameters, "n")
-	if err == nil {
-		topic_filter.NetworkIds = network_ids
+	return !has_included || included
+}
+
+// Returns true if the value matches the pattern, where `*` is any sequence of characters.
+func match_glob(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
 	}
-	groups, err := message.GetStringList(parameters, "g")
-	if err == nil {
-		topic_filter.Groups = groups
+
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
 	}
-	smartcontracts, err := message.GetStringList(parameters, "s")
-	if err == nil {
-		topic_filter.Smartcontracts = smartcontracts
+	value = value[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
 	}
-	methods, err := message.GetStringList(parameters, "m")
-	if err == nil {
-		topic_filter.Methods = methods
+
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
+
+// The literal characters and `*`, optionally negated by `!`.
+var filter_value_regexp = regexp.MustCompile(`^!?[A-Za-z0-9 _*-]+$`)
+
+// Returns true if the filter value is a literal or a pattern of the literal characters and `*`,
+// optionally negated by `!`.
+func isFilterValue(val string) bool {
+	return filter_value_regexp.MatchString(val)
+}
+
+// This method converts the topic filter string to the TopicFilter.
+//
+// The topic filter string is provided in the following string format:
+//
+//	`o:<organization>,<organization>;p:<project>;n:<network id>;g:<group>;s:<smartcontract>;v:<version>;m:<method>;e:<event>`
+//
+// ----------------------
+//
+// Rules
+//
+//   - every path name is optional, but can be set only once.
+//   - the path has one or more values separated by `,`.
+//   - Order of the path names does not matter: o:org;p:proj == p:proj;o:org
+//   - The values are literals or the patterns, see TopicFilter.
+//   - The empty string and the trailing `;` are allowed.
+//
+// The topic filter is returned in the canonical form, therefore
+// ParseFilterString(filter.ToString()) equals to filter.Canonical().
+func ParseFilterString(filter_string string) (TopicFilter, error) {
+	t := TopicFilter{}
+	filter_string = strings.TrimSuffix(filter_string, ";")
+	if len(filter_string) == 0 {
+		return t, nil
 	}
-	logs, err := message.GetStringList(parameters, "e")
-	if err == nil {
-		topic_filter.Events = logs
+
+	for _, part := range strings.Split(filter_string, ";") {
+		keyValue := strings.Split(part, ":")
+		if len(keyValue) != 2 {
+			return TopicFilter{}, fmt.Errorf("invalid key:value in the topic filter string")
+		}
+
+		if !isPathName(keyValue[0]) {
+			return TopicFilter{}, fmt.Errorf("invalid path name: %s", keyValue[0])
+		}
+
+		values := strings.Split(keyValue[1], ",")
+		for _, value := range values {
+			if !isFilterValue(value) {
+				return TopicFilter{}, fmt.Errorf("invalid value for path name '%s': %s", keyValue[0], value)
+			}
+		}
+
+		path := t.path(keyValue[0])
+		if len(*path) > 0 {
+			return TopicFilter{}, fmt.Errorf("the duplicate path name: %s", keyValue[0])
+		}
+		*path = values
 	}
 
-	return &topic_filter, nil
+	return t.Canonical(), nil
+}
+
+// Returns the values of the path name
+func (t *TopicFilter) path(pathName string) *[]string {
+	switch pathName {
+	case "o":
+		return &t.Organizations
+	case "p":
+		return &t.Projects
+	case "n":
+		return &t.NetworkIds
+	case "g":
+		return &t.Groups
+	case "s":
+		return &t.Smartcontracts
+	case "v":
+		return &t.Versions
+	case "m":
+		return &t.Methods
+	default:
+		return &t.Events
+	}
 }
@@ -0,0 +1,110 @@
+package topic
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Returns the random filter value, see isFilterValue().
+// The values are short, so that the duplicates are common.
+func random_filter_value(rand *rand.Rand) string {
+	characters := "ab*"
+	value := make([]byte, 1+rand.Intn(3))
+	for i := range value {
+		value[i] = characters[rand.Intn(len(characters))]
+	}
+	if rand.Intn(4) == 0 {
+		return "!" + string(value)
+	}
+	return string(value)
+}
+
+// The random topic filter, with the unsorted and duplicate values
+type filter_value struct {
+	Filter TopicFilter
+}
+
+func (filter_value) Generate(rand *rand.Rand, size int) reflect.Value {
+	filter := TopicFilter{}
+	for _, pathName := range path_names {
+		amount := rand.Intn(4)
+		if amount == 0 {
+			continue
+		}
+		values := make([]string, amount)
+		for i := range values {
+			values[i] = random_filter_value(rand)
+		}
+		*filter.path(pathName) = values
+	}
+
+	return reflect.ValueOf(filter_value{Filter: filter})
+}
+
+func TestFilterStringRoundTrip(t *testing.T) {
+	property := func(value filter_value) bool {
+		parsed, err := ParseFilterString(value.Filter.ToString())
+		if err != nil {
+			t.Logf("failed to parse %s: %v", value.Filter.ToString(), err)
+			return false
+		}
+		return reflect.DeepEqual(parsed, value.Filter.Canonical())
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Encodes the JSON of the filter, then decodes it as the request parameters.
+func json_round_trip(t *testing.T, parameters map[string]interface{}) map[string]interface{} {
+	bytes, err := json.Marshal(parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestFilterJSONRoundTrip(t *testing.T) {
+	property := func(value filter_value) bool {
+		parameters := json_round_trip(t, value.Filter.ToJSON())
+		parsed, err := ParseJSONToTopicFilter(parameters)
+		if err != nil {
+			t.Logf("failed to parse %v: %v", parameters, err)
+			return false
+		}
+		return reflect.DeepEqual(*parsed, value.Filter.Canonical())
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// The older SDK versions sent the projects as "p:"
+func TestFilterJSONLegacyProjects(t *testing.T) {
+	property := func(value filter_value) bool {
+		parameters := json_round_trip(t, value.Filter.ToJSON())
+		if projects, ok := parameters["p"]; ok {
+			delete(parameters, "p")
+			parameters["p:"] = projects
+		}
+		parsed, err := ParseJSONToTopicFilter(parameters)
+		if err != nil {
+			t.Logf("failed to parse %v: %v", parameters, err)
+			return false
+		}
+		return reflect.DeepEqual(*parsed, value.Filter.Canonical())
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,92 @@
+package topic
+
+import (
+	"errors"
+	"sync"
+)
+
+// The handler of the routed data.
+// The topic is the full topic of the data, with the method or the event.
+// The data is the categorized transaction or log.
+type Handler func(topic Topic, data interface{})
+
+type route struct {
+	filter  TopicFilter
+	handler Handler
+}
+
+// Routes the data to the handlers by their topic filters.
+// One subscription could feed several independent consumers this way.
+//
+// The router is safe for concurrent use.
+type Router struct {
+	mu     sync.RWMutex
+	routes []route
+}
+
+func NewRouter() *Router {
+	return &Router{routes: make([]route, 0)}
+}
+
+// Add the handler of the topics that match the filter, see TopicFilter.Match().
+// The data is passed to every handler that matches the topic, in the order they were added.
+func (router *Router) Handle(filter TopicFilter, handler Handler) {
+	router.mu.Lock()
+	router.routes = append(router.routes, route{filter: filter.Canonical(), handler: handler})
+	router.mu.Unlock()
+}
+
+// Calls the handlers that match the topic.
+// Returns the number of the called handlers.
+func (router *Router) Route(topic Topic, data interface{}) int {
+	router.mu.RLock()
+	routes := router.routes
+	router.mu.RUnlock()
+
+	called := 0
+	for _, route := range routes {
+		if route.filter.Match(topic) {
+			route.handler(topic, data)
+			called++
+		}
+	}
+
+	return called
+}
+
+// Routes the transaction by the topic string of its smartcontract and the method name.
+func (router *Router) RouteMethod(topic_string string, method string, data interface{}) (int, error) {
+	topic, err := full_topic(topic_string)
+	if err != nil {
+		return 0, err
+	}
+	topic.Method = method
+
+	return router.Route(topic, data), nil
+}
+
+// Routes the log by the topic string of its smartcontract and the event name.
+func (router *Router) RouteEvent(topic_string string, event string, data interface{}) (int, error) {
+	topic, err := full_topic(topic_string)
+	if err != nil {
+		return 0, err
+	}
+	topic.Event = event
+
+	return router.Route(topic, data), nil
+}
+
+// Parses the topic string of the smartcontract, to add the method or the event.
+func full_topic(topic_string string) (Topic, error) {
+	topic, err := ParseString(topic_string)
+	if err != nil {
+		return Topic{}, err
+	}
+	if topic.Level() < SMARTCONTRACT_LEVEL {
+		return Topic{}, errors.New("the topic string '" + topic_string + "' is not the smartcontract topic")
+	}
+	topic.Method = ""
+	topic.Event = ""
+
+	return topic, nil
+}
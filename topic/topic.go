@@ -17,6 +17,7 @@ type (
 		NetworkId     string
 		Group         string
 		Smartcontract string
+		Version       string // optional, the deployment of the smartcontract. The latest one if empty.
 		Method        string
 		Event         string
 	}
@@ -35,7 +36,7 @@ func New(o string, p string, n string, g string, s string, m string, e string) T
 }
 
 func (t *Topic) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	json := map[string]interface{}{
 		"o": t.Organization,
 		"p": t.Project,
 		"n": t.NetworkId,
@@ -44,6 +45,10 @@ func (t *Topic) ToJSON() map[string]interface{} {
 		"m": t.Method,
 		"e": t.Event,
 	}
+	if len(t.Version) > 0 {
+		json["v"] = t.Version
+	}
+	return json
 }
 
 func (t *Topic) ToString(level uint8) string {
@@ -67,6 +72,9 @@ func (t *Topic) ToString(level uint8) string {
 	}
 	if level >= 5 {
 		str += ";s:" + t.Smartcontract
+		if len(t.Version) > 0 {
+			str += ";v:" + t.Version
+		}
 	}
 	if level == 6 {
 		if len(t.Method) > 0 {
@@ -148,6 +156,11 @@ func ParseJSON(parameters map[string]interface{}) (*Topic, error) {
 		topic.Smartcontract = smartcontract
 	}
 
+	version, err := message.GetString(parameters, "v")
+	if err == nil {
+		topic.Version = version
+	}
+
 	method, err := message.GetString(parameters, "m")
 	if err == nil {
 		topic.Method = method
@@ -162,7 +175,7 @@ func ParseJSON(parameters map[string]interface{}) (*Topic, error) {
 }
 
 func isPathName(name string) bool {
-	return name == "o" || name == "p" || name == "n" || name == "g" || name == "s" || name == "m" || name == "e"
+	return name == "o" || name == "p" || name == "n" || name == "g" || name == "s" || name == "v" || name == "m" || name == "e"
 }
 
 func isLiteral(val string) bool {
@@ -201,6 +214,12 @@ func (t *Topic) setNewValue(pathName string, val string) error {
 		} else {
 			t.Smartcontract = val
 		}
+	case "v":
+		if len(t.Version) > 0 {
+			return fmt.Errorf("the duplicate version path name. already set as " + t.Version)
+		} else {
+			t.Version = val
+		}
 	case "m":
 		if len(t.Method) > 0 {
 			return fmt.Errorf("the duplicate method path name. already set as " + t.Method)
@@ -224,6 +243,7 @@ func (t *Topic) setNewValue(pathName string, val string) error {
 //
 //	`o:<organization>;p:<project>;n:<network id>;g:<group>;s:<smartcontract>;m:<method>`
 //	`o:<organization>;p:<project>;n:<network id>;g:<group>;s:<smartcontract>;e:<event>`
+//	`o:<organization>;p:<project>;n:<network id>;g:<group>;s:<smartcontract>;v:<version>;m:<method>`
 //
 // ----------------------
 //
@@ -231,6 +251,7 @@ func (t *Topic) setNewValue(pathName string, val string) error {
 //
 //   - the topic string can have either `method` or `event` but not both at the same time.
 //   - Topic string should contain atleast 'organization' and 'project'
+//   - the `version` is optional, it selects the deployment of the smartcontract. Without it, the latest deployment is used.
 //   - Order of the path names does not matter: o:org;p:proj == p:proj;o:org
 //   - The values between `<` and `>` are literals and should return true by `isLiteral(literal)` function
 func ParseString(topicString string) (Topic, error) {
@@ -240,8 +261,8 @@ func ParseString(topicString string) (Topic, error) {
 		return Topic{}, fmt.Errorf("path should have atleast two elements")
 	}
 
-	if length > 6 {
-		return Topic{}, fmt.Errorf("at most topic string can have six path names")
+	if length > 7 {
+		return Topic{}, fmt.Errorf("at most topic string can have seven path names")
 	}
 
 	t := Topic{}
@@ -0,0 +1,79 @@
+package topic
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+const literal_characters = "abcXYZ019 _-"
+
+// Returns the random non empty literal, see isLiteral()
+func random_literal(rand *rand.Rand) string {
+	literal := make([]byte, 1+rand.Intn(8))
+	for i := range literal {
+		literal[i] = literal_characters[rand.Intn(len(literal_characters))]
+	}
+	return string(literal)
+}
+
+// The random topic of the full level, with either the method or the event
+type full_topic_value struct {
+	Topic Topic
+}
+
+func (full_topic_value) Generate(rand *rand.Rand, size int) reflect.Value {
+	t := Topic{
+		Organization:  random_literal(rand),
+		Project:       random_literal(rand),
+		NetworkId:     random_literal(rand),
+		Group:         random_literal(rand),
+		Smartcontract: random_literal(rand),
+	}
+	if rand.Intn(2) == 0 {
+		t.Version = random_literal(rand)
+	}
+	if rand.Intn(2) == 0 {
+		t.Method = random_literal(rand)
+	} else {
+		t.Event = random_literal(rand)
+	}
+
+	return reflect.ValueOf(full_topic_value{Topic: t})
+}
+
+func TestTopicStringRoundTrip(t *testing.T) {
+	property := func(value full_topic_value) bool {
+		parsed, err := ParseString(value.Topic.ToString(FULL_LEVEL))
+		if err != nil {
+			t.Logf("failed to parse %s: %v", value.Topic.ToString(FULL_LEVEL), err)
+			return false
+		}
+		return parsed == value.Topic
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTopicJSONRoundTrip(t *testing.T) {
+	property := func(value full_topic_value) bool {
+		parameters := json_round_trip(t, value.Topic.ToJSON())
+		if _, ok := parameters["v"]; ok != (value.Topic.Version != "") {
+			t.Logf("the version of %v is not in %v", value.Topic, parameters)
+			return false
+		}
+		parsed, err := ParseJSON(parameters)
+		if err != nil {
+			t.Logf("failed to parse %v: %v", parameters, err)
+			return false
+		}
+		return *parsed == value.Topic
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}